@@ -17,6 +17,9 @@ limitations under the License.
 package config
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"io"
 	"time"
 
@@ -32,8 +35,9 @@ type Config struct {
 	Log        *LogConfig           `json:"log,omitempty" yaml:"log,omitempty"`
 }
 
+// String returns the configuration with the secrets masked.
 func (c Config) String() string {
-	out, err := yaml.Marshal(c)
+	out, err := yaml.Marshal(c.redacted())
 	if err != nil {
 		panic(err)
 	}
@@ -101,9 +105,11 @@ func (c Config) DumpSampleConfig(w io.Writer) error {
 
 // Credentials is the representation of git authentication
 type Credentials struct {
-	Username   string     `json:"username,omitempty" yaml:"username,omitempty"`
-	Password   string     `json:"password,omitempty" yaml:"password,omitempty"`
-	PrivateKey PrivateKey `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	// PasswordFile is read for the password
+	PasswordFile string     `json:"password_file,omitempty" yaml:"password_file,omitempty"`
+	PrivateKey   PrivateKey `json:"private_key,omitempty" yaml:"private_key,omitempty"`
 }
 
 // PrivateKey is the representation of private key used for the authentication
@@ -112,6 +118,8 @@ type PrivateKey struct {
 	SkipHostKeyCheck bool   `json:"skip_host_key_check,omitempty" yaml:"skip_host_key_check,omitempty"`
 	Username         string `json:"username,omitempty" yaml:"username,omitempty"`
 	Password         string `json:"password,omitempty" yaml:"password,omitempty"`
+	// PasswordFile is read for the password of the key
+	PasswordFile string `json:"password_file,omitempty" yaml:"password_file,omitempty"`
 }
 
 // Hook is the configuration for hooks
@@ -121,22 +129,107 @@ type Hook struct {
 	// Specific to polling
 	Interval time.Duration `json:"interval" yaml:"interval"`
 
+	// Debounce collapses the changes of the repository notified within the
+	// window into one KV update
+	Debounce time.Duration `json:"debounce,omitempty" yaml:"debounce,omitempty"`
+
 	// Specific to webhooks
 	URL string `json:"url,omitempty" yaml:"url"`
+	// Secret verifies the signature or the token of the webhook requests
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// SecretFile is read for the secret
+	SecretFile string `json:"secret_file,omitempty" yaml:"secret_file,omitempty"`
 }
 
+// Array formats used by expand_keys to store the arrays
+const (
+	ArrayFormatIndex = "index" // every item is stored under its index key
+	ArrayFormatJoin  = "join"  // scalar items are joined with the array_separator into a single value
+	ArrayFormatJSON  = "json"  // the array is stored as a JSON encoded value
+)
+
+// Policies for the files exceeding the max_value_size
+const (
+	LargeFilesFail  = "fail"  // the sync fails with an error
+	LargeFilesSkip  = "skip"  // the file is skipped with a warning
+	LargeFilesChunk = "chunk" // the file is split into numbered chunks described by a manifest key
+)
+
+// Encodings of the binary files
+const (
+	BinaryEncodingNone   = "none"   // the content is stored as it is
+	BinaryEncodingBase64 = "base64" // the content is stored base64 encoded
+)
+
+// DefaultMaxValueSize is the default size limit of the Consul KV values.
+const DefaultMaxValueSize = 512 * 1024
+
 // Repo is the configuration for the repository
 type Repo struct {
-	Name           string      `json:"name" yaml:"name"`
-	URL            string      `json:"url" yaml:"url"`
-	Branches       []string    `json:"branches" yaml:"branches"`
-	Hooks          []*Hook     `json:"hooks" yaml:"hooks"`
-	SourceRoot     string      `json:"source_root" yaml:"source_root"`
-	MountPoint     string      `json:"mount_point" yaml:"mount_point"`
-	ExpandKeys     bool        `json:"expand_keys,omitempty" yaml:"expand_keys,omitempty"`
-	SkipBranchName bool        `json:"skip_branch_name,omitempty" yaml:"skip_branch_name,omitempty"`
-	SkipRepoName   bool        `json:"skip_repo_name,omitempty" yaml:"skip_repo_name,omitempty"`
-	Credentials    Credentials `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+	Name            string          `json:"name" yaml:"name"`
+	URL             string          `json:"url" yaml:"url"`
+	Branches        []string        `json:"branches" yaml:"branches"`             // names or glob patterns of the branches
+	Tags            []string        `json:"tags,omitempty" yaml:"tags,omitempty"` // names or glob patterns of the tags
+	Hooks           []*Hook         `json:"hooks" yaml:"hooks"`
+	SourceRoot      string          `json:"source_root" yaml:"source_root"`
+	MountPoint      string          `json:"mount_point" yaml:"mount_point"`
+	Include         []string        `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude         []string        `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	ExpandKeys      bool            `json:"expand_keys,omitempty" yaml:"expand_keys,omitempty"`
+	StripExtensions StripExtensions `json:"strip_extensions,omitempty" yaml:"strip_extensions,omitempty"`
+	ArrayFormat     string          `json:"array_format,omitempty" yaml:"array_format,omitempty"`
+	ArraySeparator  string          `json:"array_separator,omitempty" yaml:"array_separator,omitempty"`
+	SkipBranchName  bool            `json:"skip_branch_name,omitempty" yaml:"skip_branch_name,omitempty"`
+	SkipRepoName    bool            `json:"skip_repo_name,omitempty" yaml:"skip_repo_name,omitempty"`
+	Prune           bool            `json:"prune,omitempty" yaml:"prune,omitempty"`
+	MaxValueSize    int             `json:"max_value_size,omitempty" yaml:"max_value_size,omitempty"`
+	LargeFiles      string          `json:"large_files,omitempty" yaml:"large_files,omitempty"`
+	BinaryEncoding  string          `json:"binary_encoding,omitempty" yaml:"binary_encoding,omitempty"`
+	Credentials     Credentials     `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
+// StripExtensions is the strip_extensions option of the repository. It is either
+// a boolean stripping any extension or the list of the extensions to strip.
+type StripExtensions struct {
+	Enabled    bool
+	Extensions []string
+}
+
+// IsZero reports whether the extensions are kept.
+func (s StripExtensions) IsZero() bool {
+	return !s.Enabled && len(s.Extensions) == 0
+}
+
+// UnmarshalYAML accepts either a boolean or a list of extensions.
+func (s *StripExtensions) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		s.Enabled = true
+		return value.Decode(&s.Extensions)
+	}
+	return value.Decode(&s.Enabled)
+}
+
+// MarshalYAML returns the list of extensions if set, the boolean otherwise.
+func (s StripExtensions) MarshalYAML() (interface{}, error) {
+	if len(s.Extensions) > 0 {
+		return s.Extensions, nil
+	}
+	return s.Enabled, nil
+}
+
+// UnmarshalJSON accepts either a boolean or a list of extensions.
+func (s *StripExtensions) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		s.Enabled = true
+		return json.Unmarshal(data, &s.Extensions)
+	}
+	return json.Unmarshal(data, &s.Enabled)
+}
+
+// MarshalJSON returns the list of extensions if set, the boolean otherwise.
+func (s StripExtensions) MarshalJSON() ([]byte, error) {
+	value, _ := s.MarshalYAML()
+	return json.Marshal(value)
 }
 
 func (r *Repo) String() string {
@@ -150,14 +243,55 @@ func (r *Repo) String() string {
 type WebhookServerConfig struct {
 	Address string `json:"address,omitempty" yaml:"address,omitempty"`
 	Port    int    `json:"port" yaml:"port"`
+	// Workers is the number of the webhook jobs processed concurrently
+	Workers int `json:"workers,omitempty" yaml:"workers,omitempty"`
+	// Token is the bearer token required by the sync endpoint
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// TokenFile is read for the token
+	TokenFile string `json:"token_file,omitempty" yaml:"token_file,omitempty"`
+	// TLS serves the webhooks over HTTPS when the certificate is set
+	TLS WebhookTLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+// WebhookTLSConfig is the TLS configuration of the git hooks server. The
+// files are reloaded when they change.
+type WebhookTLSConfig struct {
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	// ClientCAFile enables the mutual TLS, the client certificates must be signed by its CAs
+	ClientCAFile string `json:"client_ca_file,omitempty" yaml:"client_ca_file,omitempty"`
+	MinVersion   string `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+}
+
+// Enabled returns whether the webhooks are served over HTTPS.
+func (c WebhookTLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// TLSVersion returns the TLS version of the name accepted by min_version.
+func TLSVersion(name string) (uint16, bool) {
+	switch name {
+	case "1.0":
+		return tls.VersionTLS10, true
+	case "1.1":
+		return tls.VersionTLS11, true
+	case "1.2":
+		return tls.VersionTLS12, true
+	case "1.3":
+		return tls.VersionTLS13, true
+	}
+	return 0, false
 }
 
 // ConsulConfig is the configuration for the Consul client
 type ConsulConfig struct {
 	Address   string          `json:"address,omitempty" yaml:"address,omitempty"` // default to 127.0.0.1:8500 according to consul go SDK
 	Token     string          `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenFile string          `json:"token_file,omitempty" yaml:"token_file,omitempty"` // read for the token
 	SSLEnable bool            `json:"ssl_enable" yaml:"ssl_enable"`
 	TLSConfig ConsulTLSConfig `json:"tls_config" yaml:"tls_config,omitempty"`
+	// AtomicSync rolls back the already committed transaction slices when a later slice fails
+	AtomicSync bool `json:"atomic_sync,omitempty" yaml:"atomic_sync,omitempty"`
 }
 
 // ConsulTLSConfig used for consul mTLS auth
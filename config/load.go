@@ -18,8 +18,10 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -60,6 +62,12 @@ func Load(file string) (*Config, error) {
 		return nil, err
 	}
 
+	// Read the secrets referenced by the configuration
+	err = config.resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+
 	logger.Info("Setting configuration with sane defaults")
 	config.setDefaultConfig()
 	config.setDefaultLogConfig()
@@ -71,52 +79,239 @@ func Load(file string) (*Config, error) {
 	return config, nil
 }
 
-// Check for the validity of the configuration file
+// Check for the validity of the configuration file, returns the first problem
 func (c *Config) checkConfig() error {
-	for _, repo := range c.Repos {
+	if problems := c.problems(); len(problems) > 0 {
+		return problems[0].err
+	}
+	return nil
+}
+
+// A problem of the configuration, found at the path of the option made of the
+// keys and the indexes leading to it
+type problem struct {
+	path []interface{}
+	err  error
+}
+
+// Returns all the problems of the configuration
+func (c *Config) problems() []problem {
+	var problems []problem
+	report := func(err error, path ...interface{}) {
+		problems = append(problems, problem{path: path, err: err})
+	}
+
+	// Check on the log level, which would make the logger panic
+	if _, err := log.ParseLevel(c.Log.Level); c.Log.Level != "" && err != nil {
+		report(fmt.Errorf("Invalid log level: %s. Supported levels are debug, info, warn, error and fatal", c.Log.Level), "log", "level")
+	}
+
+	if c.Webhook.Workers < 0 {
+		report(fmt.Errorf("Invalid number of webhook workers: %d", c.Webhook.Workers), "webhook", "workers")
+	}
+
+	// Check on the webhook TLS
+	webhookTLS := c.Webhook.TLS
+	if (webhookTLS.CertFile == "") != (webhookTLS.KeyFile == "") {
+		report(fmt.Errorf("Webhook TLS requires both cert_file and key_file"), "webhook", "tls")
+	}
+	if webhookTLS.ClientCAFile != "" && !webhookTLS.Enabled() {
+		report(fmt.Errorf("Webhook TLS client_ca_file requires cert_file and key_file"), "webhook", "tls", "client_ca_file")
+	}
+	if _, ok := TLSVersion(webhookTLS.MinVersion); !ok {
+		report(fmt.Errorf("Invalid webhook TLS min_version: %s. Supported versions are 1.0, 1.1, 1.2 and 1.3", webhookTLS.MinVersion), "webhook", "tls", "min_version")
+	}
+
+	names := make(map[string]bool, len(c.Repos))
+	for i, repo := range c.Repos {
 		// Check on name
 		if repo.Name == "" {
-			return fmt.Errorf("Repository array object missing \"name\" value")
+			report(fmt.Errorf("Repository array object missing \"name\" value"), "repos", i)
+		}
+
+		// Check on duplicate names, the repositories would share the local clone
+		if repo.Name != "" && names[repo.Name] {
+			report(fmt.Errorf("Duplicate repository name: %s", repo.Name), "repos", i, "name")
 		}
+		names[repo.Name] = true
 
 		// Check on Url
 		if repo.URL == "" {
-			return fmt.Errorf("%s does no have a repository URL", repo.Name)
+			report(fmt.Errorf("%s does no have a repository URL", repo.Name), "repos", i)
+		}
+
+		// Check on the branch and tag patterns
+		branches := make(map[string]bool, len(repo.Branches))
+		for j, branch := range repo.Branches {
+			if ValidatePattern(branch) != nil {
+				report(fmt.Errorf("Invalid branch pattern for the %s repository: %s", repo.Name, branch), "repos", i, "branches", j)
+			}
+			if branches[branch] {
+				report(fmt.Errorf("Duplicate branch for the %s repository: %s", repo.Name, branch), "repos", i, "branches", j)
+			}
+			branches[branch] = true
+		}
+		tags := make(map[string]bool, len(repo.Tags))
+		for j, tag := range repo.Tags {
+			if ValidatePattern(tag) != nil {
+				report(fmt.Errorf("Invalid tag pattern for the %s repository: %s", repo.Name, tag), "repos", i, "tags", j)
+			}
+			if tags[tag] {
+				report(fmt.Errorf("Duplicate tag for the %s repository: %s", repo.Name, tag), "repos", i, "tags", j)
+			}
+			tags[tag] = true
 		}
 
 		// Check on hooks
-		for _, hook := range repo.Hooks {
+		for j, hook := range repo.Hooks {
 			if hook.Type != "polling" && hook.Type != "webhook" {
-				return fmt.Errorf("Invalid hook type: %s", hook.Type)
+				report(fmt.Errorf("Invalid hook type: %s", hook.Type), "repos", i, "hooks", j, "type")
 			}
 
 			if hook.Type == "polling" && hook.Interval <= 0 {
-				return fmt.Errorf("Invalid interval: %s. Hook interval must be greater than zero", hook.Interval)
+				report(fmt.Errorf("Invalid interval: %s. Hook interval must be greater than zero", hook.Interval), "repos", i, "hooks", j, "interval")
+			}
+
+			if hook.Debounce < 0 {
+				report(fmt.Errorf("Invalid debounce: %s. Hook debounce must not be negative", hook.Debounce), "repos", i, "hooks", j, "debounce")
+			}
+		}
+
+		// Check on array_format
+		switch repo.ArrayFormat {
+		case ArrayFormatIndex, ArrayFormatJoin, ArrayFormatJSON:
+		default:
+			report(fmt.Errorf("Invalid array_format for the %s repository: %s", repo.Name, repo.ArrayFormat), "repos", i, "array_format")
+		}
+
+		// Check on the include and exclude patterns
+		for j, pattern := range repo.Include {
+			if ValidatePattern(pattern) != nil {
+				report(fmt.Errorf("Invalid include pattern for the %s repository: %s", repo.Name, pattern), "repos", i, "include", j)
+			}
+		}
+		for j, pattern := range repo.Exclude {
+			if ValidatePattern(pattern) != nil {
+				report(fmt.Errorf("Invalid exclude pattern for the %s repository: %s", repo.Name, pattern), "repos", i, "exclude", j)
+			}
+		}
+
+		// Check on the large and binary files
+		if repo.MaxValueSize < 0 {
+			report(fmt.Errorf("Invalid max_value_size for the %s repository: %d", repo.Name, repo.MaxValueSize), "repos", i, "max_value_size")
+		}
+		switch repo.LargeFiles {
+		case LargeFilesFail, LargeFilesSkip, LargeFilesChunk:
+		default:
+			report(fmt.Errorf("Invalid large_files for the %s repository: %s", repo.Name, repo.LargeFiles), "repos", i, "large_files")
+		}
+		switch repo.BinaryEncoding {
+		case BinaryEncodingNone, BinaryEncodingBase64:
+		default:
+			report(fmt.Errorf("Invalid binary_encoding for the %s repository: %s", repo.Name, repo.BinaryEncoding), "repos", i, "binary_encoding")
+		}
+
+		// Check on prune, which must not remove the keys of other branches or repositories
+		if repo.Prune {
+			if repo.SkipBranchName && (len(repo.Branches)+len(repo.Tags) > 1 || hasPattern(repo.Branches) || hasPattern(repo.Tags)) {
+				report(fmt.Errorf("Invalid prune option for the %s repository - skip_branch_name is enabled for multiple branches or tags", repo.Name), "repos", i, "prune")
+			}
+			if repo.SkipBranchName && repo.SkipRepoName && repo.MountPoint == "" {
+				report(fmt.Errorf("Invalid prune option for the %s repository - the keys have no prefix", repo.Name), "repos", i, "prune")
 			}
 		}
 
 		// Check on mount_point
 		if repo.MountPoint != "" {
 			if strings.HasPrefix(repo.MountPoint, "/") {
-				return fmt.Errorf("Invalid mount point format for the %s repository - found \"/\" in the beginning of the path", repo.Name)
+				report(fmt.Errorf("Invalid mount point format for the %s repository - found \"/\" in the beginning of the path", repo.Name), "repos", i, "mount_point")
 			}
 			if !strings.HasSuffix(repo.MountPoint, "/") {
-				return fmt.Errorf("Invalid mount point format for the %s repository - missing trailing \"/\"", repo.Name)
+				report(fmt.Errorf("Invalid mount point format for the %s repository - missing trailing \"/\"", repo.Name), "repos", i, "mount_point")
 			}
 		}
 
 		// Check on source_root
 		if repo.SourceRoot != "" {
 			if !strings.HasPrefix(repo.SourceRoot, "/") {
-				return fmt.Errorf("Invalid source_root format for the %s repository - missing \"/\" in the beginning of the path", repo.Name)
+				report(fmt.Errorf("Invalid source_root format for the %s repository - missing \"/\" in the beginning of the path", repo.Name), "repos", i, "source_root")
 			}
 			if !strings.HasSuffix(repo.SourceRoot, "/") {
-				return fmt.Errorf("Invalid source_root format for the %s repository - missing trailing \"/\"", repo.Name)
+				report(fmt.Errorf("Invalid source_root format for the %s repository - missing trailing \"/\"", repo.Name), "repos", i, "source_root")
 			}
 		}
 	}
 
-	return nil
+	// Check on the overlapping keys of the repositories, which would overwrite
+	// or prune the keys of each other. The duplicate repositories are already reported.
+	var unique []int
+	for i, repo := range c.Repos {
+		duplicate := false
+		for _, j := range unique {
+			other := c.Repos[j]
+			if other.Name == repo.Name {
+				duplicate = true
+				break
+			}
+			if prefix, ok := overlappingPrefix(other, repo); ok {
+				report(fmt.Errorf("The keys of the %s repository overlap with the keys of the %s repository under %q", repo.Name, other.Name, prefix), "repos", i, "mount_point")
+			}
+		}
+		if !duplicate {
+			unique = append(unique, i)
+		}
+	}
+
+	return problems
+}
+
+// Returns the KV prefixes of the branches and the tags of the repository. The
+// prefix of a pattern stops before its first segment with a wildcard.
+func keyPrefixes(repo *Repo) []string {
+	repoName := repo.Name
+	if repo.SkipRepoName {
+		repoName = ""
+	}
+	if repo.SkipBranchName {
+		return []string{path.Join(repo.MountPoint, repoName)}
+	}
+	prefixes := make([]string, 0, len(repo.Branches)+len(repo.Tags))
+	for _, ref := range append(append([]string{}, repo.Branches...), repo.Tags...) {
+		var literal []string
+		for _, segment := range strings.Split(ref, "/") {
+			if hasPattern([]string{segment}) {
+				break
+			}
+			literal = append(literal, segment)
+		}
+		prefixes = append(prefixes, path.Join(repo.MountPoint, repoName, path.Join(literal...)))
+	}
+	return prefixes
+}
+
+// Returns true if any of the names is a glob pattern.
+func hasPattern(names []string) bool {
+	for _, name := range names {
+		if strings.ContainsAny(name, "*?[\\") {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the shortest of the overlapping KV prefixes of the repositories.
+func overlappingPrefix(repo, other *Repo) (string, bool) {
+	for _, prefix := range keyPrefixes(repo) {
+		for _, otherPrefix := range keyPrefixes(other) {
+			if len(otherPrefix) < len(prefix) {
+				prefix, otherPrefix = otherPrefix, prefix
+			}
+			if prefix == "" || prefix == otherPrefix || strings.HasPrefix(otherPrefix, prefix+"/") {
+				return prefix, true
+			}
+		}
+	}
+	return "", false
 }
 
 // Return a configuration with sane defaults
@@ -131,11 +326,21 @@ func (c *Config) setDefaultConfig() {
 		c.Webhook.Port = 9000
 	}
 
+	// Set the default number of the webhook workers
+	if c.Webhook.Workers == 0 {
+		c.Webhook.Workers = 4
+	}
+
+	// Set the default minimum TLS version of the webhooks
+	if c.Webhook.TLS.MinVersion == "" {
+		c.Webhook.TLS.MinVersion = "1.2"
+	}
+
 	// For each repo, set default branch and hook
 	for _, repo := range c.Repos {
 		branch := []string{"main"}
-		// If there are no branches, set it to master
-		if len(repo.Branches) == 0 {
+		// If there are no branches nor tags, set it to main
+		if len(repo.Branches) == 0 && len(repo.Tags) == 0 {
 			repo.Branches = branch
 		}
 
@@ -149,6 +354,25 @@ func (c *Config) setDefaultConfig() {
 			repo.Hooks = append(repo.Hooks, hook)
 		}
 
+		// Store the arrays under the index keys by default
+		if repo.ArrayFormat == "" {
+			repo.ArrayFormat = ArrayFormatIndex
+		}
+		if repo.ArraySeparator == "" {
+			repo.ArraySeparator = ","
+		}
+
+		// Fail on the files which Consul would reject anyway
+		if repo.MaxValueSize == 0 {
+			repo.MaxValueSize = DefaultMaxValueSize
+		}
+		if repo.LargeFiles == "" {
+			repo.LargeFiles = LargeFilesFail
+		}
+		if repo.BinaryEncoding == "" {
+			repo.BinaryEncoding = BinaryEncodingNone
+		}
+
 		// expand tilde home directory for key path
 		if repo.Credentials.PrivateKey.Key != "" {
 			if strings.HasPrefix(repo.Credentials.PrivateKey.Key, "~/") {
@@ -167,3 +391,17 @@ func (c *Config) setDefaultLogConfig() {
 		c.Log.Level = "info"
 	}
 }
+
+// ValidatePattern returns an error if the glob pattern is empty or any of its
+// path segments is malformed.
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -17,12 +17,15 @@ limitations under the License.
 package config
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/discard"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func init() {
@@ -42,3 +45,215 @@ func TestLoadInvalidConfig(t *testing.T) {
 	_, err := Load(file)
 	assert.Error(t, err)
 }
+
+func TestCheckConfigArrayFormat(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example"}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+	assert.Equal(t, ArrayFormatIndex, cfg.Repos[0].ArrayFormat)
+
+	cfg.Repos[0].ArrayFormat = "csv"
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigPrune(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example", Prune: true, Branches: []string{"main", "develop"}}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+
+	cfg.Repos[0].SkipBranchName = true
+	assert.Error(t, cfg.checkConfig())
+
+	cfg.Repos[0].Branches = []string{"main"}
+	assert.NoError(t, cfg.checkConfig())
+
+	cfg.Repos[0].SkipRepoName = true
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigLargeFiles(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example"}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+	assert.Equal(t, DefaultMaxValueSize, cfg.Repos[0].MaxValueSize)
+	assert.Equal(t, LargeFilesFail, cfg.Repos[0].LargeFiles)
+	assert.Equal(t, BinaryEncodingNone, cfg.Repos[0].BinaryEncoding)
+
+	cfg.Repos[0].LargeFiles = "truncate"
+	assert.Error(t, cfg.checkConfig())
+
+	cfg.Repos[0].LargeFiles = LargeFilesChunk
+	cfg.Repos[0].BinaryEncoding = "hex"
+	assert.Error(t, cfg.checkConfig())
+
+	cfg.Repos[0].BinaryEncoding = BinaryEncodingBase64
+	cfg.Repos[0].MaxValueSize = -1
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigWorkers(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example"}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+	assert.Equal(t, 4, cfg.Webhook.Workers)
+
+	cfg.Webhook.Workers = -1
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigDuplicates(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{Level: "info"},
+		Repos: []*Repo{
+			{Name: "example", URL: "./example", Branches: []string{"main", "develop"}},
+			{Name: "team", URL: "./team"},
+		},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+
+	cfg.Log.Level = "verbose"
+	assert.Error(t, cfg.checkConfig())
+	cfg.Log.Level = "info"
+
+	cfg.Repos[0].Branches = []string{"main", "main"}
+	assert.Error(t, cfg.checkConfig())
+	cfg.Repos[0].Branches = []string{"main", "develop"}
+
+	cfg.Repos[1].Name = "example"
+	assert.Error(t, cfg.checkConfig())
+	cfg.Repos[1].Name = "team"
+
+	// The keys of the team repository land under example/develop
+	cfg.Repos[1].SkipRepoName = true
+	cfg.Repos[1].SkipBranchName = true
+	cfg.Repos[1].MountPoint = "example/develop/team/"
+	assert.Error(t, cfg.checkConfig())
+	cfg.Repos[1].MountPoint = "example/"
+	assert.Error(t, cfg.checkConfig())
+	cfg.Repos[1].MountPoint = "team/"
+	assert.NoError(t, cfg.checkConfig())
+}
+
+func TestCheckConfigWebhookTLS(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example"}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+	assert.Equal(t, "1.2", cfg.Webhook.TLS.MinVersion)
+	assert.False(t, cfg.Webhook.TLS.Enabled())
+
+	cfg.Webhook.TLS.ClientCAFile = "ca.crt"
+	assert.Error(t, cfg.checkConfig())
+
+	cfg.Webhook.TLS.CertFile = "tls.crt"
+	assert.Error(t, cfg.checkConfig())
+
+	cfg.Webhook.TLS.KeyFile = "tls.key"
+	assert.NoError(t, cfg.checkConfig())
+	assert.True(t, cfg.Webhook.TLS.Enabled())
+
+	cfg.Webhook.TLS.MinVersion = "1.4"
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigDebounce(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example", Hooks: []*Hook{{Type: "webhook", Debounce: time.Minute}}}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+
+	cfg.Repos[0].Hooks[0].Debounce = -time.Second
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigPatterns(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example", Include: []string{"app/**/*.yml"}, Exclude: []string{"*.tmpl"}}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+
+	cfg.Repos[0].Exclude = []string{"app/[invalid"}
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestCheckConfigBranchPatterns(t *testing.T) {
+	cfg := &Config{
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+		Repos:   []*Repo{{Name: "example", URL: "./example", Tags: []string{"v*"}}},
+	}
+	cfg.setDefaultConfig()
+	assert.NoError(t, cfg.checkConfig())
+	// The tags are tracked without the default branch
+	assert.Empty(t, cfg.Repos[0].Branches)
+
+	cfg.Repos[0].Branches = []string{"release/*"}
+	assert.NoError(t, cfg.checkConfig())
+
+	cfg.Repos[0].Tags = []string{"v[1-"}
+	assert.Error(t, cfg.checkConfig())
+	cfg.Repos[0].Tags = []string{"v*"}
+
+	// The keys of every matching branch would be pruned under the same prefix
+	cfg.Repos[0].Prune = true
+	cfg.Repos[0].SkipBranchName = true
+	cfg.Repos[0].Tags = nil
+	assert.Error(t, cfg.checkConfig())
+	cfg.Repos[0].Prune = false
+	cfg.Repos[0].SkipBranchName = false
+
+	// The keys of the team repository land under example/release
+	cfg.Repos = append(cfg.Repos, &Repo{Name: "team", URL: "./team", MountPoint: "example/release/", SkipRepoName: true, SkipBranchName: true})
+	cfg.setDefaultConfig()
+	assert.Error(t, cfg.checkConfig())
+}
+
+func TestStripExtensions(t *testing.T) {
+	var repo Repo
+	assert.NoError(t, yaml.Unmarshal([]byte("strip_extensions: true"), &repo))
+	assert.Equal(t, StripExtensions{Enabled: true}, repo.StripExtensions)
+
+	repo = Repo{}
+	assert.NoError(t, yaml.Unmarshal([]byte("strip_extensions: [.conf, txt]"), &repo))
+	assert.Equal(t, StripExtensions{Enabled: true, Extensions: []string{".conf", "txt"}}, repo.StripExtensions)
+
+	out, err := yaml.Marshal(&repo)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "strip_extensions:\n    - .conf\n    - txt\n")
+
+	repo = Repo{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"strip_extensions": [".conf"]}`), &repo))
+	assert.Equal(t, StripExtensions{Enabled: true, Extensions: []string{".conf"}}, repo.StripExtensions)
+
+	repo = Repo{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"strip_extensions": false}`), &repo))
+	assert.True(t, repo.StripExtensions.IsZero())
+	assert.Error(t, json.Unmarshal([]byte(`{"strip_extensions": "yes"}`), &repo))
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix of the secret values read from a file
+const secretFilePrefix = "file://"
+
+// Replaces the secrets of the configuration by the values they reference.
+func (c *Config) resolveSecrets() error {
+	var err error
+	if c.Consul.Token, err = resolveSecret("consul:token", c.Consul.Token, c.Consul.TokenFile); err != nil {
+		return err
+	}
+	if c.Webhook.Token, err = resolveSecret("webhook:token", c.Webhook.Token, c.Webhook.TokenFile); err != nil {
+		return err
+	}
+	for _, repo := range c.Repos {
+		credentials := &repo.Credentials
+		name := fmt.Sprintf("repos:%s:credentials:password", repo.Name)
+		if credentials.Password, err = resolveSecret(name, credentials.Password, credentials.PasswordFile); err != nil {
+			return err
+		}
+		name = fmt.Sprintf("repos:%s:credentials:private_key:password", repo.Name)
+		if credentials.PrivateKey.Password, err = resolveSecret(name, credentials.PrivateKey.Password, credentials.PrivateKey.PasswordFile); err != nil {
+			return err
+		}
+		for _, hook := range repo.Hooks {
+			name = fmt.Sprintf("repos:%s:hooks:secret", repo.Name)
+			if hook.Secret, err = resolveSecret(name, hook.Secret, hook.SecretFile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the secret of the option. The ${VAR} references of the value are
+// replaced by the environment variables, then a file:// value is replaced by
+// the content of the file. The file of the matching *_file option is read
+// instead when set. The errors never contain the secret itself.
+func resolveSecret(name, value, file string) (string, error) {
+	if file != "" {
+		if value != "" {
+			return "", fmt.Errorf("Both %s and %s_file are set", name, name)
+		}
+		path, err := expandEnv(name+"_file", file)
+		if err != nil {
+			return "", err
+		}
+		return readSecretFile(name+"_file", path)
+	}
+
+	value, err := expandEnv(name, value)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(value, secretFilePrefix) {
+		return readSecretFile(name, strings.TrimPrefix(value, secretFilePrefix))
+	}
+	return value, nil
+}
+
+// Returns the content of the secret file without the trailing newline.
+func readSecretFile(name, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Cannot read the secret of %s: %s", name, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// Replaces the ${VAR} references by the environment variables, which must be
+// set. A $${VAR} is kept as the literal ${VAR}.
+func expandEnv(name, value string) (string, error) {
+	var expanded strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			expanded.WriteString(value)
+			return expanded.String(), nil
+		}
+		if start > 0 && value[start-1] == '$' {
+			expanded.WriteString(value[:start] + "{")
+			value = value[start+2:]
+			continue
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("Invalid environment variable reference in %s - missing \"}\"", name)
+		}
+		variable := value[start+2 : start+end]
+		env, ok := os.LookupEnv(variable)
+		if !ok {
+			return "", fmt.Errorf("Environment variable %q referenced by %s is not set", variable, name)
+		}
+		expanded.WriteString(value[:start] + env)
+		value = value[start+end+1:]
+	}
+}
+
+// Returns a copy of the configuration with the secrets masked, so that it can
+// be logged.
+func (c Config) redacted() Config {
+	if c.Consul != nil {
+		consul := *c.Consul
+		consul.Token = redact(consul.Token)
+		c.Consul = &consul
+	}
+	if c.Webhook != nil {
+		webhook := *c.Webhook
+		webhook.Token = redact(webhook.Token)
+		c.Webhook = &webhook
+	}
+	repos := make([]*Repo, 0, len(c.Repos))
+	for _, repo := range c.Repos {
+		if repo == nil {
+			continue
+		}
+		copied := *repo
+		copied.Credentials.Password = redact(copied.Credentials.Password)
+		copied.Credentials.PrivateKey.Password = redact(copied.Credentials.PrivateKey.Password)
+		copied.Hooks = make([]*Hook, 0, len(repo.Hooks))
+		for _, hook := range repo.Hooks {
+			if hook == nil {
+				continue
+			}
+			hookCopy := *hook
+			hookCopy.Secret = redact(hookCopy.Secret)
+			copied.Hooks = append(copied.Hooks, &hookCopy)
+		}
+		repos = append(repos, &copied)
+	}
+	c.Repos = repos
+	return c
+}
+
+// Masks the secret, an unset secret stays empty.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("GIT2CONSUL_TEST_SECRET", "s3cr3t")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+	t.Setenv("GIT2CONSUL_TEST_DIR", filepath.Dir(secretFile))
+
+	for _, tc := range []struct {
+		name, value, file string
+		secret            string
+		invalid           bool
+	}{
+		{name: "literal", value: "plain", secret: "plain"},
+		{name: "dollar", value: "pa$$word$", secret: "pa$$word$"},
+		{name: "environment", value: "${GIT2CONSUL_TEST_SECRET}", secret: "s3cr3t"},
+		{name: "interpolated", value: "prefix-${GIT2CONSUL_TEST_SECRET}-suffix", secret: "prefix-s3cr3t-suffix"},
+		{name: "escaped", value: "$${GIT2CONSUL_TEST_SECRET}", secret: "${GIT2CONSUL_TEST_SECRET}"},
+		{name: "unset environment", value: "${GIT2CONSUL_TEST_UNSET}", invalid: true},
+		{name: "unterminated reference", value: "${GIT2CONSUL_TEST_SECRET", invalid: true},
+		{name: "file url", value: "file://" + secretFile, secret: "from-file"},
+		{name: "file url with environment", value: "file://${GIT2CONSUL_TEST_DIR}/secret", secret: "from-file"},
+		{name: "missing file url", value: "file://" + secretFile + "-missing", invalid: true},
+		{name: "file option", file: secretFile, secret: "from-file"},
+		{name: "file option with environment", file: "${GIT2CONSUL_TEST_DIR}/secret", secret: "from-file"},
+		{name: "missing file option", file: secretFile + "-missing", invalid: true},
+		{name: "value and file option", value: "plain", file: secretFile, invalid: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := resolveSecret("token", tc.value, tc.file)
+			if tc.invalid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.secret, secret)
+		})
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("git-password\n"), 0600))
+	t.Setenv("GIT2CONSUL_TEST_TOKEN", "consul-token")
+	t.Setenv("GIT2CONSUL_TEST_HOOK_SECRET", "hook-secret")
+
+	file := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+repos:
+  - name: example
+    url: ./example
+    credentials:
+      username: git
+      password_file: `+passwordFile+`
+      private_key:
+        key: /tmp/id_rsa
+        password: file://`+passwordFile+`
+    hooks:
+      - type: webhook
+        secret: ${GIT2CONSUL_TEST_HOOK_SECRET}
+consul:
+  token: ${GIT2CONSUL_TEST_TOKEN}
+webhook:
+  token: literal-token
+`), 0600))
+
+	cfg, err := Load(file)
+	require.NoError(t, err)
+	assert.Equal(t, "consul-token", cfg.Consul.Token)
+	assert.Equal(t, "literal-token", cfg.Webhook.Token)
+	assert.Equal(t, "git-password", cfg.Repos[0].Credentials.Password)
+	assert.Equal(t, "git-password", cfg.Repos[0].Credentials.PrivateKey.Password)
+	assert.Equal(t, "hook-secret", cfg.Repos[0].Hooks[0].Secret)
+
+	// The secrets are masked when the configuration is logged
+	out := cfg.String()
+	for _, secret := range []string{"consul-token", "literal-token", "git-password", "hook-secret"} {
+		assert.NotContains(t, out, secret)
+	}
+	assert.Contains(t, out, passwordFile)
+	assert.Equal(t, "consul-token", cfg.Consul.Token)
+	assert.Equal(t, "hook-secret", cfg.Repos[0].Hooks[0].Secret)
+
+	// An unset environment variable fails the load
+	require.NoError(t, os.WriteFile(file, []byte("consul:\n  token: ${GIT2CONSUL_TEST_UNSET}\n"), 0600))
+	_, err = Load(file)
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem of the configuration file. The line and the
+// column of the option are set when they are known.
+type ValidationError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("%s: %s", e.File, e.Err)
+	case e.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the configuration file and returns all its problems sorted
+// by position, including the unknown options which Load ignores. The secrets
+// are not resolved, so the file can be checked without them. An error is
+// returned when the file cannot be read.
+func Validate(file string) ([]*ValidationError, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		Consul:  &ConsulConfig{},
+		Webhook: &WebhookServerConfig{},
+		Log:     &LogConfig{},
+	}
+	var errs []*ValidationError
+	var malformed bool
+	ext := filepath.Ext(file)
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(content, config)
+		errs = jsonErrors(file, content, err)
+		malformed = err != nil && !errors.As(err, new(*json.UnmarshalTypeError))
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, config)
+		errs = yamlErrors(file, err)
+		malformed = err != nil && !errors.As(err, new(*yaml.TypeError))
+	default:
+		return []*ValidationError{{File: file, Err: fmt.Errorf("invalid config file extension: %s", ext)}}, nil
+	}
+	// The options cannot be checked when the file is malformed
+	if malformed {
+		return errs, nil
+	}
+
+	// The JSON files are parsed as YAML too, for the positions of the options
+	root := &yaml.Node{}
+	var document yaml.Node
+	if yaml.Unmarshal(content, &document) == nil && len(document.Content) > 0 {
+		root = document.Content[0]
+	}
+
+	for _, node := range unknownOptions(root, reflect.TypeOf(config)) {
+		errs = append(errs, &ValidationError{File: file, Line: node.Line, Column: node.Column, Err: fmt.Errorf("Unknown option %q", node.Value)})
+	}
+
+	if len(config.Repos) == 0 {
+		errs = append(errs, &ValidationError{File: file, Line: root.Line, Column: root.Column, Err: errors.New("No repositories provided in the configuration")})
+	}
+	config.setDefaultConfig()
+	config.setDefaultLogConfig()
+	for _, problem := range config.problems() {
+		node := locate(root, problem.path)
+		errs = append(errs, &ValidationError{File: file, Line: node.Line, Column: node.Column, Err: problem.err})
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs, nil
+}
+
+// Returns the node of the option at the path, or the node of its closest
+// parent when the option is not set in the file.
+func locate(node *yaml.Node, path []interface{}) *yaml.Node {
+	for _, step := range path {
+		var next *yaml.Node
+		switch step := step.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == step {
+						next = node.Content[i+1]
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && step < len(node.Content) {
+				next = node.Content[step]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// Returns the key nodes of the options which are not fields of the type.
+func unknownOptions(node *yaml.Node, typ reflect.Type) []*yaml.Node {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	// The types decoding themselves accept their own values
+	if reflect.PtrTo(typ).Implements(reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()) {
+		return nil
+	}
+
+	var unknown []*yaml.Node
+	switch {
+	case typ.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			unknown = append(unknown, unknownOptions(item, typ.Elem())...)
+		}
+	case typ.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := make(map[string]reflect.Type, typ.NumField())
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+			fields[name] = typ.Field(i).Type
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				unknown = append(unknown, key)
+				continue
+			}
+			unknown = append(unknown, unknownOptions(value, fieldType)...)
+		}
+	}
+	return unknown
+}
+
+// Returns the errors of the YAML decoding with their lines.
+func yamlErrors(file string, err error) []*ValidationError {
+	if err == nil {
+		return nil
+	}
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	errs := make([]*ValidationError, 0, len(messages))
+	for _, message := range messages {
+		validationErr := &ValidationError{File: file}
+		message = strings.TrimPrefix(message, "yaml: ")
+		if rest := strings.TrimPrefix(message, "line "); rest != message {
+			if i := strings.Index(rest, ": "); i > 0 {
+				if line, err := strconv.Atoi(rest[:i]); err == nil {
+					validationErr.Line = line
+					message = rest[i+2:]
+				}
+			}
+		}
+		validationErr.Err = errors.New(message)
+		errs = append(errs, validationErr)
+	}
+	return errs
+}
+
+// Returns the error of the JSON decoding with its position.
+func jsonErrors(file string, content []byte, err error) []*ValidationError {
+	if err == nil {
+		return nil
+	}
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return []*ValidationError{{File: file, Err: err}}
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return []*ValidationError{{File: file, Line: line, Column: column, Err: err}}
+}
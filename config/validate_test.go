@@ -0,0 +1,125 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes the configuration file and returns its validation errors.
+func validate(t *testing.T, name, content string) []string {
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+	errs, err := Validate(file)
+	require.NoError(t, err)
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error()[len(file):])
+	}
+	return messages
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, validate(t, "config.yaml", `
+repos:
+  - name: example
+    url: ./example
+    branches: [main]
+`))
+
+	assert.Equal(t, []string{
+		`:2:10: Invalid log level: verbose. Supported levels are debug, info, warn, error and fatal`,
+		`:4:3: Unknown option "prot"`,
+		`:10:9: Duplicate branch for the example repository: main`,
+		`:12:9: Invalid interval: 0s. Hook interval must be greater than zero`,
+		`:13:9: Unknown option "secrett"`,
+		`:14:11: Duplicate repository name: example`,
+		`:17:18: The keys of the team repository overlap with the keys of the example repository under "example/main"`,
+	}, validate(t, "config.yaml", `log:
+  level: verbose
+webhook:
+  prot: 9000
+repos:
+  - name: example
+    url: ./example
+    branches:
+      - main
+      - main
+    hooks:
+      - type: polling
+        secrett: abc
+  - name: example
+    url: ./other
+  - name: team
+    mount_point: example/
+    skip_repo_name: true
+    url: ./team
+    branches: [main]
+`))
+}
+
+func TestValidateJSON(t *testing.T) {
+	assert.Equal(t, []string{
+		`:5:7: Unknown option "branch"`,
+		`:6:23: Invalid array_format for the example repository: csv`,
+	}, validate(t, "config.json", `{
+  "repos": [
+    {
+      "name": "example",
+      "branch": "main",
+      "array_format": "csv",
+      "url": "./example"
+    }
+  ]
+}`))
+
+	assert.Equal(t, []string{
+		`:3:5: Repository array object missing "name" value`,
+		`:4:16: json: cannot unmarshal number into Go struct field Config.repos.0.name of type string`,
+	}, validate(t, "config.json", `{
+  "repos": [
+    {
+      "name": 1,
+      "url": "./example"
+    }
+  ]
+}`))
+}
+
+func TestValidateMalformed(t *testing.T) {
+	assert.Equal(t, []string{
+		`:3: mapping values are not allowed in this context`,
+	}, validate(t, "config.yaml", "repos:\n  - name: example\n    url: ./example: main\n"))
+
+	assert.Equal(t, []string{
+		`:3: cannot unmarshal !!str ` + "`main`" + ` into []string`,
+		`:5:20: Invalid hook type: pull`,
+	}, validate(t, "config.yaml", "repos:\n  - name: example\n    branches: main\n    url: ./example\n    hooks: [{type: pull}]\n"))
+
+	assert.Equal(t, []string{
+		`: No repositories provided in the configuration`,
+	}, validate(t, "config.yaml", ""))
+
+	assert.Equal(t, []string{
+		`: invalid config file extension: .toml`,
+	}, validate(t, "config.toml", ""))
+}
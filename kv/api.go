@@ -24,14 +24,19 @@ import (
 // Handler interface for Key-Value store.
 type Handler interface {
 	PutKV(repository.Repo, string, []byte) error
+	PutKVFlags(repository.Repo, string, []byte, uint64) error
 	DeleteKV(repository.Repo, string) error
 	DeleteTreeKV(repository.Repo, string) error
+	ListKV(repository.Repo, string) ([]string, error)
 	HandleUpdate(repository.Repo) error
+	ForceUpdate(repository.Repo) error
+	AppliedOps() int
 }
 
 // API minimal Consul KV api implementation
 type API interface {
 	Get(string, *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
+	List(string, *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
 	Put(*api.KVPair, *api.WriteOptions) (*api.WriteMeta, error)
 	Txn(api.KVTxnOps, *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error)
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package kv
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -34,9 +35,61 @@ func (h *KVHandler) putBranch(repo repository.Repo, branch plumbing.ReferenceNam
 	// h, _ := repo.Head()
 	// bn, _ := h.Branch().Name()
 	// log.Debugf("(consul) pushBranch(): Branch: %s Head: %s", bn, h.Target().String())
+	// Push every file even if one of them fails, so all the failures are logged
+	var failed fileErrors
+	err := walkBranch(repo, func(file File) error {
+		failed.add(h.logger, file.Create(h, repo))
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Debug("PUT branch error")
+		return err
+	}
+
+	return failed.err()
+}
+
+// fileErrors collects the errors of the files of a branch which could not be
+// pushed to the KV.
+type fileErrors struct {
+	first error
+	count int
+}
+
+// Logs and records the error of a file, if any.
+func (e *fileErrors) add(logger *log.Entry, err error) {
+	if err == nil {
+		return
+	}
+	logger.Errorf("%s", err)
+	if e.first == nil {
+		e.first = err
+	}
+	e.count++
+}
+
+// Returns the first recorded error along with the number of failed files.
+func (e *fileErrors) err() error {
+	switch e.count {
+	case 0:
+		return nil
+	case 1:
+		return e.first
+	default:
+		return fmt.Errorf("%d files failed, first error: %w", e.count, e.first)
+	}
+}
+
+// Walks the files of the checked out branch under the source_root.
+func walkBranch(repo repository.Repo, fn func(File) error) error {
 	workdir := repository.WorkDir(repo)
 	sourceRoot := repo.GetConfig().SourceRoot
-	pushFile := func(fullpath string, info os.FileInfo, err error) error {
+	// The owners of the keys of the stripped files are shared by the walk
+	owners, err := newKeyOwners(repo)
+	if err != nil {
+		return err
+	}
+	walkFile := func(fullpath string, info os.FileInfo, err error) error {
 		// Walk error
 		if err != nil {
 			return err
@@ -52,19 +105,16 @@ func (h *KVHandler) putBranch(repo repository.Repo, branch plumbing.ReferenceNam
 			return nil
 		}
 
-		file := Init(fullpath, repo)
-		err = file.Create(h, repo)
+		// Skip the files filtered out by the include and exclude patterns
+		name, err := filepath.Rel(workdir, fullpath)
 		if err != nil {
-			h.logger.Errorf("%s", err)
+			return err
+		}
+		if !owners.filter.Match(filepath.ToSlash(name)) {
+			return nil
 		}
-		return nil
-	}
-	workdir = filepath.Join(workdir, sourceRoot)
-	err := filepath.Walk(workdir, pushFile)
-	if err != nil {
-		log.WithError(err).Debug("PUT branch error")
-		return err
-	}
 
-	return nil
+		return fn(owners.init(fullpath))
+	}
+	return filepath.Walk(filepath.Join(workdir, sourceRoot), walkFile)
 }
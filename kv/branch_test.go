@@ -67,3 +67,47 @@ func TestPutBranch(t *testing.T) {
 	})
 	assert.NoError(t, err)
 }
+
+// TestPutBranchFilter verifies the include and exclude patterns and the ignore file are honored.
+func TestPutBranchFilter(t *testing.T) {
+	repoPath := t.TempDir()
+	files := map[string]string{
+		"README.md":           "readme",
+		"app/config.yml":      "config",
+		"app/config.yml.tmpl": "template",
+		"app/local.yml":       "local",
+		"ci/pipeline.yml":     "ci",
+		".git2consulignore":   "# local overrides\n*.yml\n!config.yml\n",
+	}
+	for name, content := range files {
+		err := os.MkdirAll(filepath.Dir(filepath.Join(repoPath, name)), 0o700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{
+		Include: []string{"app/**"},
+		Exclude: []string{"*.tmpl"},
+	}}
+	kv := &mocks.KV{T: t}
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+
+	assert.NoError(t, handler.putBranch(repo, repo.Branch()))
+	assert.NoError(t, handler.Commit())
+
+	pairs, _, _ := kv.List("", nil)
+	var keys []string
+	for _, pair := range pairs {
+		keys = append(keys, pair.Key)
+	}
+	assert.Equal(t, []string{"repository_mock/master/app/config.yml"}, keys)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// Create function creates the KV store entries based on the file content.
+func (f *DotenvFile) Create(kv Handler, repo repository.Repo) error {
+	return createEntries(kv, repo, f)
+}
+
+// Update functions updates the KV store based on the file content.
+func (f *DotenvFile) Update(kv Handler, repo repository.Repo) error {
+	return updateEntries(kv, repo, f)
+}
+
+// Delete removes the key-value pairs from the KV store under given prefix.
+func (f *DotenvFile) Delete(kv Handler, repo repository.Repo) error {
+	return deleteEntries(kv, repo, f)
+}
+
+// GetPath returns the path to the file.
+func (f *DotenvFile) GetPath() string {
+	return f.path
+}
+
+// Evaluates the dotenv file. Double quoted values support escape sequences
+// and can span multiple lines, single quoted values are taken literally.
+func (f *DotenvFile) decode(content []byte) (interface{}, error) {
+	keys := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing \"=\" separator", lineNumber)
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", lineNumber)
+		}
+		value := strings.TrimSpace(line[i+1:])
+
+		switch {
+		case strings.HasPrefix(value, "\""):
+			start := lineNumber
+			// Read the following lines until the closing quote
+			end := closingDoubleQuote(value)
+			for end < 0 {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated double quoted value", start)
+				}
+				lineNumber++
+				value += "\n" + scanner.Text()
+				end = closingDoubleQuote(value)
+			}
+			value = unescapeDotenv(value[1:end])
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quoted value", lineNumber)
+			}
+			value = value[1 : end+1]
+		default:
+			// Unquoted values can be followed by an inline comment
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		keys[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Returns the index of the closing quote of the double quoted value or -1.
+func closingDoubleQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDotenvFileEntries verifies evaluation of the dotenv files.
+func TestDotenvFileEntries(t *testing.T) {
+	content := "# comment\n" +
+		"APP_NAME=orders\n" +
+		"export APP_ENV=production\n" +
+		"APP_PORT=8080 # inline comment\n" +
+		"APP_GREETING=\"Hello\\n\\\"World\\\"\" # comment\n" +
+		"APP_LITERAL='no $expansion\\n'\n" +
+		"APP_CERT=\"-----BEGIN-----\n" +
+		"line\n" +
+		"-----END-----\"\n" +
+		"APP_EMPTY=\n"
+	entries, err := evaluate(&DotenvFile{}, []byte(content))
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"APP_NAME":     "orders",
+		"APP_ENV":      "production",
+		"APP_PORT":     "8080",
+		"APP_GREETING": "Hello\n\"World\"",
+		"APP_LITERAL":  "no $expansion\\n",
+		"APP_CERT":     "-----BEGIN-----\nline\n-----END-----",
+		"APP_EMPTY":    "",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+}
+
+// TestDotenvFileInvalidLine ensures lines without the separator are reported.
+func TestDotenvFileInvalidLine(t *testing.T) {
+	_, err := evaluate(&DotenvFile{}, []byte("APP_NAME=orders\nAPP_ENV\n"))
+	assert.EqualError(t, err, "line 2: missing \"=\" separator")
+
+	_, err = evaluate(&DotenvFile{}, []byte("APP_NAME=\"orders\n"))
+	assert.EqualError(t, err, "line 1: unterminated double quoted value")
+}
+
+// TestDotenvFilePrefix ensures the ".env" dotfile keeps its name in the key prefix.
+func TestDotenvFilePrefix(t *testing.T) {
+	var repo repository.Repo = &mocks.Repo{Config: &config.Repo{}}
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, ".env")
+	err := os.WriteFile(filePath, []byte("APP_NAME=orders\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = make(map[string][]byte)
+	err = (&DotenvFile{filePath}).Create(&mockHandler{t: t, filePath: filePath}, repo)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", string(keys[filepath.Join(dir, ".env", "APP_NAME")]))
+}
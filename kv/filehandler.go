@@ -17,12 +17,14 @@ limitations under the License.
 package kv
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
+	"github.com/KohlsTechnology/git2consul-go/config"
 	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/apex/log"
 	"gopkg.in/yaml.v3"
 )
 
@@ -35,9 +37,18 @@ type File interface {
 	GetPath() string
 }
 
+// expandedFile is a File which content is evaluated into multiple
+// key-value pairs stored under the file path stripped of its extension.
+type expandedFile interface {
+	File
+	decode(content []byte) (interface{}, error)
+}
+
 // TextFile structure
 type TextFile struct {
 	path string
+	// owners of the keys of the stripped files, resolved by the file if nil
+	owners *keyOwners
 }
 
 // YAMLFile structure
@@ -45,15 +56,57 @@ type YAMLFile struct {
 	path string
 }
 
+// JSONFile structure
+type JSONFile struct {
+	path string
+}
+
+// PropertiesFile structure
+type PropertiesFile struct {
+	path string
+}
+
+// INIFile structure
+type INIFile struct {
+	path string
+}
+
+// DotenvFile structure
+type DotenvFile struct {
+	path string
+}
+
+// TOMLFile structure
+type TOMLFile struct {
+	path string
+}
+
+// HCLFile structure
+type HCLFile struct {
+	path string
+}
+
 // Init initializes new instance of File interface based on it's extension.
 func Init(path string, repo repository.Repo) File {
-	config := repo.GetConfig()
-	expandKeys := config.ExpandKeys
+	expandKeys := repo.GetConfig().ExpandKeys
 	var f File
 	ext := filepath.Ext(path)
 	if expandKeys {
-		if ext == ".yml" || ext == ".yaml" {
+		switch ext {
+		case ".yml", ".yaml":
 			f = &YAMLFile{path: path}
+		case ".json":
+			f = &JSONFile{path: path}
+		case ".properties":
+			f = &PropertiesFile{path: path}
+		case ".ini":
+			f = &INIFile{path: path}
+		case ".env":
+			f = &DotenvFile{path: path}
+		case ".toml":
+			f = &TOMLFile{path: path}
+		case ".hcl":
+			f = &HCLFile{path: path}
 		}
 	}
 	if f == nil {
@@ -77,15 +130,35 @@ func (f *TextFile) GetPath() string {
 
 // Create function creates the KV store entries based on the file content.
 func (f *TextFile) Create(kv Handler, repo repository.Repo) error {
+	keyPath := stripExtension(repo, f.path)
+	if keyPath != f.path {
+		owner, err := f.keyOwner(repo)
+		if err != nil {
+			return err
+		}
+		if owner != f.path {
+			log.Errorf("Skipping %s, it maps to the same key as %s", f.path, owner)
+			return nil
+		}
+	}
 	content, err := getContent(f)
 	if err != nil {
 		return err
 	}
-	err = kv.PutKV(repo, f.path, content)
-	if err != nil {
-		return err
+	return putFileContent(kv, repo, keyPath, content)
+}
+
+// Returns the text file owning the key the file maps to once its extension
+// is stripped.
+func (f *TextFile) keyOwner(repo repository.Repo) (string, error) {
+	if f.owners == nil {
+		owners, err := newKeyOwners(repo)
+		if err != nil {
+			return "", err
+		}
+		f.owners = owners
 	}
-	return nil
+	return f.owners.owner(f.path)
 }
 
 // Update functions updates the KV store based on the file content.
@@ -95,83 +168,138 @@ func (f *TextFile) Update(kv Handler, repo repository.Repo) error {
 
 // Delete removes the key-value pair from the KV store.
 func (f *TextFile) Delete(kv Handler, repo repository.Repo) error {
-	err := kv.DeleteKV(repo, f.path)
+	keyPath := stripExtension(repo, f.path)
+	if keyPath != f.path {
+		// Another file mapping to the same key takes over the key
+		owner, err := f.keyOwner(repo)
+		if err != nil {
+			return err
+		}
+		if owner != "" {
+			if owner < f.path {
+				return nil
+			}
+			return (&TextFile{path: owner, owners: f.owners}).Create(kv, repo)
+		}
+	}
+	err := kv.DeleteKV(repo, keyPath)
 	if err != nil {
 		return err
 	}
+	if repo.GetConfig().LargeFiles == config.LargeFilesChunk {
+		return kv.DeleteTreeKV(repo, filepath.Join(keyPath, chunksDir))
+	}
 	return nil
 }
 
 // Create function creates the KV store entries based on the file content.
 func (f *YAMLFile) Create(kv Handler, repo repository.Repo) error {
-	content, err := getContent(f)
-	if err != nil {
-		return err
-	}
-	yamlTree := make(map[interface{}]interface{})
-	err = yaml.Unmarshal(content, &yamlTree)
+	return createEntries(kv, repo, f)
+}
+
+func (f *YAMLFile) decode(content []byte) (interface{}, error) {
+	yamlTree := &yaml.Node{}
+	err := yaml.Unmarshal(content, yamlTree)
 	if err != nil {
-		return err
-	}
-	path := f.GetPath()
-	extension := filepath.Ext(path)
-	fileName := strings.TrimSuffix(path, extension)
-	for key, value := range entriesToKV(yamlTree) {
-		err = kv.PutKV(repo, filepath.Join(fileName, key), value)
-		if err != nil {
-			return err
-		}
+		return nil, err
 	}
-	return nil
+	return yamlTree, nil
 }
 
 // Update functions updates the KV store based on the file content.
 func (f *YAMLFile) Update(kv Handler, repo repository.Repo) error {
-	f.Delete(kv, repo) //nolint:errcheck
-	return f.Create(kv, repo)
+	return updateEntries(kv, repo, f)
 }
 
 // Delete removes the key-value pairs from the KV store under given prefix.
 func (f *YAMLFile) Delete(kv Handler, repo repository.Repo) error {
+	return deleteEntries(kv, repo, f)
+}
+
+// GetPath returns the path to the file.
+func (f *YAMLFile) GetPath() string {
+	return f.path
+}
+
+// Returns the file path without its extension, which is used as the prefix
+// for the keys of the expanded files. Dotfiles like ".env" keep their name.
+func entriesPrefix(f File) string {
 	path := f.GetPath()
-	extension := filepath.Ext(path)
-	fileName := strings.TrimSuffix(path, extension)
-	err := kv.DeleteTreeKV(repo, fileName)
+	prefix := strings.TrimSuffix(path, filepath.Ext(path))
+	if strings.HasSuffix(prefix, string(filepath.Separator)) {
+		return path
+	}
+	return prefix
+}
+
+// Evaluates the file content and pushes the entries to the KV under the file prefix.
+func createEntries(kv Handler, repo repository.Repo, f expandedFile) error {
+	entries, err := readEntries(repo, f)
 	if err != nil {
 		return err
 	}
-	return nil
+	return putEntries(kv, repo, f, entries)
 }
 
-// GetPath returns the path to the file.
-func (f *YAMLFile) GetPath() string {
-	return f.path
+// Updates the entries of the expanded file. Only the keys which disappeared
+// from the file are deleted, unchanged values are skipped by the handler.
+func updateEntries(kv Handler, repo repository.Repo, f expandedFile) error {
+	entries, err := readEntries(repo, f)
+	if err != nil {
+		return err
+	}
+	prefix := entriesPrefix(f)
+	existing, err := kv.ListKV(repo, prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range existing {
+		if _, ok := entries[key]; ok {
+			continue
+		}
+		err = kv.DeleteKV(repo, filepath.Join(prefix, key))
+		if err != nil {
+			return err
+		}
+	}
+	return putEntries(kv, repo, f, entries)
 }
 
-func entriesToKV(node map[interface{}]interface{}) map[string][]byte {
-	keys := make(map[string][]byte)
-	for key, value := range node {
-		// https://go-critic.com/overview.html#typeSwitchVar-ref
-		switch value := value.(type) {
-		case string:
-			keys[key.(string)] = []byte(value)
-		case int:
-			keys[key.(string)] = []byte(strconv.Itoa(value))
-		case bool:
-			keys[key.(string)] = []byte(strconv.FormatBool(value))
-		case float64:
-			keys[key.(string)] = []byte(strconv.FormatFloat(value, 'f', 2, 64))
-		case map[interface{}]interface{}:
-			for k, v := range entriesToKV(value) {
-				keys[filepath.Join(key.(string), k)] = v
-			}
-		case []interface{}:
-			for index, item := range value {
-				for k, v := range entriesToKV(item.(map[interface{}]interface{})) {
-					keys[filepath.Join(key.(string), strconv.Itoa(index), k)] = v
-				}
-			}
+// Decodes and flattens the content of the expanded file.
+func readEntries(repo repository.Repo, f expandedFile) (map[string][]byte, error) {
+	content, err := getContent(f)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := f.decode(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate %s: %w", f.GetPath(), err)
+	}
+	entries, err := entriesToKV(tree, repo.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate %s: %w", f.GetPath(), err)
+	}
+	return entries, nil
+}
+
+func putEntries(kv Handler, repo repository.Repo, f File, entries map[string][]byte) error {
+	prefix := entriesPrefix(f)
+	for key, value := range entries {
+		err := kv.PutKV(repo, filepath.Join(prefix, key), value)
+		if err != nil {
+			return err
 		}
 	}
-	return keys
+	return nil
+}
+
+// Removes all the entries of the expanded file from the KV, including the
+// key of a scalar document stored at the file prefix itself.
+func deleteEntries(kv Handler, repo repository.Repo, f File) error {
+	prefix := entriesPrefix(f)
+	err := kv.DeleteTreeKV(repo, prefix)
+	if err != nil {
+		return err
+	}
+	return kv.DeleteKV(repo, prefix)
 }
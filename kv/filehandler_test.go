@@ -25,6 +25,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/stretchr/testify/assert"
 	yaml "gopkg.in/yaml.v3"
@@ -60,7 +62,7 @@ const (
 // * yaml
 // * text
 func TestFileHandler(t *testing.T) {
-	var repo repository.Repo
+	var repo repository.Repo = &mocks.Repo{Config: &config.Repo{}}
 	yamlTree = make(map[interface{}]interface{})
 	err := yaml.Unmarshal([]byte(content), &yamlTree)
 	if err != nil {
@@ -73,7 +75,7 @@ func TestFileHandler(t *testing.T) {
 		t.Fatal(err)
 	}
 	yamlFile = &YAMLFile{filePath}
-	textFile = &TextFile{filePath}
+	textFile = &TextFile{path: filePath}
 	handler = &mockHandler{
 		t:        t,
 		filePath: filePath,
@@ -87,7 +89,10 @@ func TestFileHandler(t *testing.T) {
 
 // testParsNodes verfies yaml file evaluation function.
 func testParseYamlEntries(t *testing.T) {
-	keys := entriesToKV(yamlTree)
+	keys, err := entriesToKV(yamlTree, &config.Repo{})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if string(keys["ei_unix_cavisson::cavisson_collector_srv"]) != "10.206.96.18" {
 		t.Fatal("Missing key or invalid value")
 	}
@@ -119,7 +124,11 @@ func testCreateYAMLFile(t *testing.T, repo repository.Repo) {
 	if len(keys) == 0 {
 		t.Fatalf("Keys empty: %+v", keys)
 	}
-	for k, v := range entriesToKV(yamlTree) {
+	entries, err := entriesToKV(yamlTree, &config.Repo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range entries {
 		if bytes.Equal(keys[filepath.Join(yamlPath, k)], v) {
 			delete(keys, filepath.Join(yamlPath, k))
 		}
@@ -148,13 +157,39 @@ func testDeleteTextFile(t *testing.T, repo repository.Repo) {
 	assert.NoError(t, err)
 }
 
+// TestInitConfigFormats ensures the configuration formats are picked by extension.
+func TestInitConfigFormats(t *testing.T) {
+	repo := &mocks.Repo{Config: &config.Repo{ExpandKeys: true}}
+	assert.IsType(t, &PropertiesFile{}, Init("app.properties", repo))
+	assert.IsType(t, &INIFile{}, Init("app.ini", repo))
+	assert.IsType(t, &DotenvFile{}, Init("production.env", repo))
+	assert.IsType(t, &DotenvFile{}, Init(".env", repo))
+	assert.IsType(t, &TOMLFile{}, Init("app.toml", repo))
+	assert.IsType(t, &HCLFile{}, Init("app.hcl", repo))
+}
+
+// evaluate decodes the content and flattens it with the default repository configuration.
+func evaluate(f expandedFile, content []byte) (map[string][]byte, error) {
+	tree, err := f.decode(content)
+	if err != nil {
+		return nil, err
+	}
+	return entriesToKV(tree, &config.Repo{})
+}
+
 func (a mockHandler) PutKV(repo repository.Repo, path string, content []byte) error {
 	keys[path] = content
 	return nil
 }
 
+func (a mockHandler) PutKVFlags(repo repository.Repo, path string, content []byte, flags uint64) error {
+	return a.PutKV(repo, path, content)
+}
+
 func (a mockHandler) DeleteKV(repo repository.Repo, path string) error {
-	if a.filePath != path {
+	// The expanded files also delete the scalar document key at their prefix.
+	prefix := strings.TrimSuffix(a.filePath, filepath.Ext(a.filePath))
+	if a.filePath != path && prefix != path {
 		return fmt.Errorf("%s differs from %s", a.filePath, path)
 	}
 	return nil
@@ -168,6 +203,18 @@ func (a mockHandler) DeleteTreeKV(repo repository.Repo, path string) error {
 	return nil
 }
 
+func (a mockHandler) ListKV(repo repository.Repo, path string) ([]string, error) {
+	return nil, nil
+}
+
 func (a mockHandler) HandleUpdate(repo repository.Repo) error {
 	return nil
 }
+
+func (a mockHandler) ForceUpdate(repo repository.Repo) error {
+	return nil
+}
+
+func (a mockHandler) AppliedOps() int {
+	return 0
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"gopkg.in/yaml.v3"
+)
+
+// flattener evaluates the decoded file content into the key-value pairs.
+// Nested objects become the segments of the key path while the arrays are
+// stored according to the array_format of the repository.
+type flattener struct {
+	arrayFormat    string
+	arraySeparator string
+	keys           map[string][]byte
+}
+
+// entriesToKV flattens the decoded file content into the key-value pairs.
+// The content is either a *yaml.Node, which scalars are kept as they are
+// written in the file, or a tree of maps, slices and scalar values.
+func entriesToKV(node interface{}, cfg *config.Repo) (map[string][]byte, error) {
+	f := &flattener{
+		arrayFormat:    cfg.ArrayFormat,
+		arraySeparator: cfg.ArraySeparator,
+		keys:           make(map[string][]byte),
+	}
+	if f.arrayFormat == "" {
+		f.arrayFormat = config.ArrayFormatIndex
+	}
+	if f.arraySeparator == "" {
+		f.arraySeparator = ","
+	}
+	var err error
+	if yamlNode, ok := node.(*yaml.Node); ok {
+		err = f.yamlNode("", yamlNode)
+	} else {
+		err = f.value("", node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.keys, nil
+}
+
+func (f *flattener) yamlNode(key string, node *yaml.Node) error {
+	switch node.Kind {
+	case 0:
+		// An empty or comment only file is an empty mapping
+		return nil
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			// A null document is an empty mapping as well
+			if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+				continue
+			}
+			err := f.yamlNode(key, child)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return f.yamlNode(key, node.Alias)
+	case yaml.ScalarNode:
+		f.keys[key] = []byte(yamlScalar(node))
+	case yaml.MappingNode:
+		pairs, err := yamlMappingPairs(node)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(pairs); i += 2 {
+			err = f.yamlNode(path.Join(key, yamlScalar(pairs[i])), pairs[i+1])
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		switch f.arrayFormat {
+		case config.ArrayFormatJoin:
+			items := make([]string, 0, len(node.Content))
+			for _, item := range node.Content {
+				item = resolveYAMLAlias(item)
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("line %d: only arrays of scalar values can be joined", item.Line)
+				}
+				items = append(items, yamlScalar(item))
+			}
+			f.keys[key] = []byte(strings.Join(items, f.arraySeparator))
+		case config.ArrayFormatJSON:
+			var value interface{}
+			err := node.Decode(&value)
+			if err != nil {
+				return err
+			}
+			return f.jsonValue(key, value)
+		default:
+			for index, item := range node.Content {
+				err := f.yamlNode(path.Join(key, strconv.Itoa(index)), item)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+	return nil
+}
+
+// Returns the key-value nodes of the mapping with the merge keys ("<<")
+// resolved. The keys defined in the mapping take precedence over the merged ones.
+func yamlMappingPairs(node *yaml.Node) ([]*yaml.Node, error) {
+	var merged, own []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := resolveYAMLAlias(node.Content[i]), node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: only scalar values are supported as keys", key.Line)
+		}
+		if key.Tag != "!!merge" {
+			own = append(own, key, value)
+			continue
+		}
+		sources := []*yaml.Node{resolveYAMLAlias(value)}
+		if sources[0].Kind == yaml.SequenceNode {
+			sources = sources[0].Content
+		}
+		for _, source := range sources {
+			source = resolveYAMLAlias(source)
+			if source.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: only mappings can be merged", source.Line)
+			}
+			pairs, err := yamlMappingPairs(source)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, pairs...)
+		}
+	}
+	return append(merged, own...), nil
+}
+
+func resolveYAMLAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// Returns the scalar as it is written in the file, nulls become empty values.
+func yamlScalar(node *yaml.Node) string {
+	if node.Tag == "!!null" {
+		return ""
+	}
+	return node.Value
+}
+
+func (f *flattener) value(key string, node interface{}) error {
+	if isScalar(node) {
+		value, err := scalarString(node)
+		if err != nil {
+			return err
+		}
+		f.keys[key] = []byte(value)
+		return nil
+	}
+
+	v := reflect.ValueOf(node)
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if !isScalar(k.Interface()) {
+				return fmt.Errorf("%s: only scalar values are supported as keys, got %T", key, k.Interface())
+			}
+			name, err := scalarString(k.Interface())
+			if err != nil {
+				return err
+			}
+			err = f.value(path.Join(key, name), v.MapIndex(k).Interface())
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		switch f.arrayFormat {
+		case config.ArrayFormatJoin:
+			items := make([]string, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				item := v.Index(i).Interface()
+				if !isScalar(item) {
+					return fmt.Errorf("%s: only arrays of scalar values can be joined", key)
+				}
+				value, err := scalarString(item)
+				if err != nil {
+					return err
+				}
+				items = append(items, value)
+			}
+			f.keys[key] = []byte(strings.Join(items, f.arraySeparator))
+		case config.ArrayFormatJSON:
+			return f.jsonValue(key, node)
+		default:
+			for i := 0; i < v.Len(); i++ {
+				err := f.value(path.Join(key, strconv.Itoa(i)), v.Index(i).Interface())
+				if err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("%s: unsupported value type %T", key, node)
+	}
+	return nil
+}
+
+func (f *flattener) jsonValue(key string, node interface{}) error {
+	value, err := jsonCompatible(node)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	f.keys[key] = content
+	return nil
+}
+
+// Converts the maps with non-string keys, which encoding/json can't handle,
+// into maps with string keys.
+func jsonCompatible(node interface{}) (interface{}, error) {
+	v := reflect.ValueOf(node)
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			name, err := scalarString(k.Interface())
+			if err != nil {
+				return nil, err
+			}
+			m[name], err = jsonCompatible(v.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if _, ok := node.([]byte); ok {
+			return node, nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			var err error
+			list[i], err = jsonCompatible(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	default:
+		return node, nil
+	}
+}
+
+func isScalar(node interface{}) bool {
+	switch node.(type) {
+	case nil, string, bool, json.Number, time.Time, []byte, fmt.Stringer:
+		return true
+	}
+	switch reflect.ValueOf(node).Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// Formats the scalar value without loss of precision.
+func scalarString(node interface{}) (string, error) {
+	switch node := node.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return node, nil
+	case []byte:
+		return string(node), nil
+	case json.Number:
+		return node.String(), nil
+	case time.Time:
+		return node.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return node.String(), nil
+	}
+	v := reflect.ValueOf(node)
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return formatFloat(v.Float(), 32), nil
+	case reflect.Float64:
+		return formatFloat(v.Float(), 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.String:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("unsupported scalar type %T", node)
+}
+
+// Returns the shortest representation of the float which parses back to the
+// same value, using the exponent only for very large or very small numbers.
+func formatFloat(value float64, bitSize int) string {
+	abs := math.Abs(value)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) || math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'g', -1, bitSize)
+	}
+	return strconv.FormatFloat(value, 'f', -1, bitSize)
+}
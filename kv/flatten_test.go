@@ -0,0 +1,146 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const flattenContent = `hosts: [a, b]
+ports:
+  80: http
+  443: https
+empty: ~
+created: 2001-12-14t21:59:43.10-05:00
+big: 18446744073709551615
+pi: 3.14159265358979
+ratio: 0.5
+defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  <<: *defaults
+  retries: 5
+`
+
+func flattenYAML(t *testing.T, content string, cfg *config.Repo) (map[string][]byte, error) {
+	node := &yaml.Node{}
+	err := yaml.Unmarshal([]byte(content), node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entriesToKV(node, cfg)
+}
+
+// TestFlattenYAMLNodes verifies every YAML node type is flattened without loss.
+func TestFlattenYAMLNodes(t *testing.T) {
+	entries, err := flattenYAML(t, flattenContent, &config.Repo{})
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"hosts/0":          "a",
+		"hosts/1":          "b",
+		"ports/80":         "http",
+		"ports/443":        "https",
+		"empty":            "",
+		"created":          "2001-12-14t21:59:43.10-05:00",
+		"big":              "18446744073709551615",
+		"pi":               "3.14159265358979",
+		"ratio":            "0.5",
+		"defaults/timeout": "30",
+		"defaults/retries": "3",
+		"service/timeout":  "30",
+		"service/retries":  "5",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+}
+
+// TestFlattenArrayFormats verifies the join and json array formats.
+func TestFlattenArrayFormats(t *testing.T) {
+	content := "hosts: [a, b]\nbackends:\n  - name: first\n    weight: 1\n"
+
+	entries, err := flattenYAML(t, "hosts: [a, b]\n", &config.Repo{ArrayFormat: config.ArrayFormatJoin, ArraySeparator: ";"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a;b", string(entries["hosts"]))
+
+	_, err = flattenYAML(t, content, &config.Repo{ArrayFormat: config.ArrayFormatJoin})
+	assert.Error(t, err)
+
+	entries, err = flattenYAML(t, content, &config.Repo{ArrayFormat: config.ArrayFormatJSON})
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, string(entries["hosts"]))
+	assert.JSONEq(t, `[{"name":"first","weight":1}]`, string(entries["backends"]))
+}
+
+// TestFlattenValues verifies flattening of the decoded trees of maps and slices.
+func TestFlattenValues(t *testing.T) {
+	tree := map[interface{}]interface{}{
+		"hosts":  []string{"a", "b"},
+		1:        "one",
+		"max":    uint64(18446744073709551615),
+		"ratio":  0.125,
+		"tiny":   1e-9,
+		"nested": map[string]interface{}{"enabled": true, "none": nil},
+	}
+	entries, err := entriesToKV(tree, &config.Repo{})
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"hosts/0":        "a",
+		"hosts/1":        "b",
+		"1":              "one",
+		"max":            "18446744073709551615",
+		"ratio":          "0.125",
+		"tiny":           "1e-09",
+		"nested/enabled": "true",
+		"nested/none":    "",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+
+	entries, err = entriesToKV(tree, &config.Repo{ArrayFormat: config.ArrayFormatJSON})
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, string(entries["hosts"]))
+}
+
+// TestFlattenUnsupportedKeys ensures complex keys are reported instead of panicking.
+func TestFlattenUnsupportedKeys(t *testing.T) {
+	_, err := flattenYAML(t, "? [a, b]\n: value\n", &config.Repo{})
+	assert.Error(t, err)
+
+	_, err = entriesToKV(map[interface{}]interface{}{
+		[2]string{"a", "b"}: "value",
+	}, &config.Repo{})
+	assert.Error(t, err)
+}
+
+// TestFlattenEmptyYAML verifies the empty, comment only and null documents
+// produce no keys.
+func TestFlattenEmptyYAML(t *testing.T) {
+	for _, content := range []string{"", "# only a comment\n", "---\n", "~\n", "--- null\n"} {
+		entries, err := flattenYAML(t, content, &config.Repo{})
+		assert.NoError(t, err, content)
+		assert.Empty(t, entries, content)
+	}
+}
@@ -20,17 +20,38 @@ import (
 	"fmt"
 
 	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/metrics"
 	"github.com/apex/log"
 	"github.com/hashicorp/consul/api"
 )
 
 const consulTxnSize = 64
 
+// consulTxnBytes limits the size of the values sent in a single transaction,
+// so the request body fits into the Consul txn_max_req_len once the values
+// are base64 encoded.
+const consulTxnBytes = 256 * 1024
+
 // KVHandler is used to manipulate the KV
 type KVHandler struct { //nolint:revive
 	API
 	api.KVTxnOps
 	logger *log.Entry
+
+	// plan records the transactions instead of executing them in dry-run mode
+	plan *Plan
+
+	// snapshot of the values stored under the prefix of the updated branch
+	snapshot *snapshot
+
+	// atomic enables the rollback of the committed slices when a later slice fails
+	atomic bool
+
+	// applied counts the operations sent to the KV store by the last update
+	applied int
+
+	// Metrics records the transactions and the synced branches
+	Metrics *metrics.Metrics
 }
 
 // TransactionIntegrityError implements error to handle any violation of transaction atomicity.
@@ -57,6 +78,7 @@ func New(cfg *config.ConsulConfig) (*KVHandler, error) {
 		API:      kv,
 		KVTxnOps: nil,
 		logger:   logger,
+		atomic:   cfg.AtomicSync,
 	}
 
 	return handler, nil
@@ -109,8 +131,10 @@ func (h *KVHandler) Put(kvPair *api.KVPair, wOptions *api.WriteOptions) (*api.Wr
 		Verb:  api.KVSet,
 		Key:   kvPair.Key,
 		Value: kvPair.Value,
+		Flags: kvPair.Flags,
 	}
 	h.KVTxnOps = append(h.KVTxnOps, txnItem)
+	h.snapshot.set(kvPair.Key, kvPair.Value)
 	return nil, nil
 }
 
@@ -121,6 +145,7 @@ func (h *KVHandler) Delete(key string, wOptions *api.WriteOptions) (*api.WriteMe
 		Key:  key,
 	}
 	h.KVTxnOps = append(h.KVTxnOps, txnItem)
+	h.snapshot.delete(key)
 	return nil, nil
 }
 
@@ -131,9 +156,20 @@ func (h *KVHandler) DeleteTree(key string, wOptions *api.WriteOptions) (*api.Wri
 		Key:  key,
 	}
 	h.KVTxnOps = append(h.KVTxnOps, txnItem)
+	h.snapshot.deleteTree(key)
 	return nil, nil
 }
 
+// Returns true if any operation other than the modify index check is queued.
+func (h *KVHandler) hasChanges() bool {
+	for _, op := range h.KVTxnOps {
+		if op.Verb != api.KVCheckIndex {
+			return true
+		}
+	}
+	return false
+}
+
 // Commit function executes set of operations from KVTxnOps as single transaction.
 func (h *KVHandler) Commit() error {
 	defer func() {
@@ -146,8 +182,13 @@ func (h *KVHandler) Commit() error {
 		// nolint: gocritic
 		kvTxnOps = append(h.KVTxnOps[1:length-1], h.KVTxnOps[0], h.KVTxnOps[length-1])
 	}
-	for _, slice := range h.splitIntoSlices(kvTxnOps, consulTxnSize) {
-		err := h.executeTransaction(slice)
+	slices := h.splitIntoSlices(kvTxnOps, consulTxnSize)
+	// A single slice is applied atomically by Consul itself
+	if h.atomic && h.plan == nil && len(slices) > 1 {
+		return h.commitAtomic(slices)
+	}
+	for _, slice := range slices {
+		_, err := h.executeTransaction(slice)
 		if err != nil {
 			return err
 		}
@@ -155,10 +196,17 @@ func (h *KVHandler) Commit() error {
 	return nil
 }
 
-func (h *KVHandler) executeTransaction(kvTxnOps api.KVTxnOps) error {
+// Executes the transaction and returns the pairs it resulted in, along with
+// their modify index. No pairs are returned in dry-run mode.
+func (h *KVHandler) executeTransaction(kvTxnOps api.KVTxnOps) (api.KVPairs, error) {
+	if h.plan != nil {
+		h.logger.Debugf("Transaction with %d items was recorded in the plan", len(kvTxnOps))
+		return nil, h.plan.apply(kvTxnOps)
+	}
 	status, response, _, err := h.Txn(kvTxnOps, nil)
+	h.Metrics.ObserveTransaction(len(kvTxnOps), txnBytes(kvTxnOps), err == nil && status)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	h.logger.Debugf("Transaction with %d items was sent to the KV store", len(kvTxnOps))
 	if !status {
@@ -166,17 +214,50 @@ func (h *KVHandler) executeTransaction(kvTxnOps api.KVTxnOps) error {
 		for _, txError := range response.Errors {
 			errMsg += fmt.Sprintf("%s\n", txError.What)
 		}
-		return &TransactionIntegrityError{fmt.Sprintf("Transaction has been rolled back due to: %s", errMsg)}
+		return nil, &TransactionIntegrityError{fmt.Sprintf("Transaction has been rolled back due to: %s", errMsg)}
 	}
-	return nil
+	for _, op := range kvTxnOps {
+		if op.Verb != api.KVCheckIndex {
+			h.applied++
+		}
+	}
+	if response == nil {
+		return nil, nil
+	}
+	return response.Results, nil
+}
+
+// AppliedOps returns the number of operations sent to the KV store by the
+// last update, the check-and-set index checks excluded.
+func (h *KVHandler) AppliedOps() int {
+	return h.applied
+}
+
+// Returns the size of the values of the operations.
+func txnBytes(kvTxnOps api.KVTxnOps) int {
+	size := 0
+	for _, op := range kvTxnOps {
+		size += len(op.Value)
+	}
+	return size
 }
 
 func (h *KVHandler) splitIntoSlices(kvTxnOps api.KVTxnOps, sliceLength int) []api.KVTxnOps {
 	var kvTxnSlices []api.KVTxnOps
 	for len(kvTxnOps) > 0 {
-		index := sliceLength
-		if index > len(kvTxnOps) {
-			index = len(kvTxnOps)
+		// Cut the slice on the number of items or on the size of the values,
+		// a single item bigger than the limit is sent on its own
+		index, size := 0, 0
+		for index < len(kvTxnOps) && index < sliceLength {
+			size += len(kvTxnOps[index].Value)
+			if index > 0 && size > consulTxnBytes {
+				break
+			}
+			index++
+		}
+		// Keep the modify index check along with the item it guards
+		if index < len(kvTxnOps) && index > 1 && kvTxnOps[index-1].Verb == api.KVCheckIndex {
+			index--
 		}
 		var slice api.KVTxnOps
 		slice = append(slice, kvTxnOps[:index]...)
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"fmt"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// Create function creates the KV store entries based on the file content.
+func (f *HCLFile) Create(kv Handler, repo repository.Repo) error {
+	return createEntries(kv, repo, f)
+}
+
+// Update functions updates the KV store based on the file content.
+func (f *HCLFile) Update(kv Handler, repo repository.Repo) error {
+	return updateEntries(kv, repo, f)
+}
+
+// Delete removes the key-value pairs from the KV store under given prefix.
+func (f *HCLFile) Delete(kv Handler, repo repository.Repo) error {
+	return deleteEntries(kv, repo, f)
+}
+
+// GetPath returns the path to the file.
+func (f *HCLFile) GetPath() string {
+	return f.path
+}
+
+// Evaluates the HCL file. The block type and its labels become the segments
+// of the key path, i.e `service "web" { port = 80 }` is evaluated to the
+// "service/web/port" key.
+func (f *HCLFile) decode(content []byte) (interface{}, error) {
+	file, err := hcl.ParseBytes(content)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("unexpected HCL root node %T", file.Node)
+	}
+	hclTree := make(map[string]interface{})
+	err = hclObjectList(hclTree, root)
+	if err != nil {
+		return nil, err
+	}
+	return hclTree, nil
+}
+
+// Converts the HCL object list into the tree of nested maps. Blocks sharing
+// the same type and labels are merged together.
+func hclObjectList(tree map[string]interface{}, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		node := tree
+		for _, key := range item.Keys[:len(item.Keys)-1] {
+			name := fmt.Sprint(key.Token.Value())
+			child, ok := node[name].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[name] = child
+			}
+			node = child
+		}
+		name := fmt.Sprint(item.Keys[len(item.Keys)-1].Token.Value())
+
+		if object, ok := item.Val.(*ast.ObjectType); ok {
+			child, ok := node[name].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[name] = child
+			}
+			err := hclObjectList(child, object.List)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := hclValue(item.Val)
+		if err != nil {
+			return err
+		}
+		node[name] = value
+	}
+	return nil
+}
+
+func hclValue(node ast.Node) (interface{}, error) {
+	switch node := node.(type) {
+	case *ast.LiteralType:
+		return node.Token.Value(), nil
+	case *ast.ObjectType:
+		tree := make(map[string]interface{})
+		err := hclObjectList(tree, node.List)
+		return tree, err
+	case *ast.ListType:
+		list := make([]interface{}, 0, len(node.List))
+		for _, item := range node.List {
+			value, err := hclValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unsupported HCL node %T at %s", node, node.Pos())
+	}
+}
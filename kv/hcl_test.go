@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHCLFileEntries verifies evaluation of the HCL files.
+func TestHCLFileEntries(t *testing.T) {
+	content := `datacenter = "dc1"
+enabled = true
+
+service "web" {
+  port = 80
+  tags = ["public", "v2"]
+
+  check {
+    interval = "10s"
+  }
+}
+
+service "api" {
+  port = 8080
+}
+
+limits = {
+  cpu = 500
+}
+`
+	entries, err := evaluate(&HCLFile{}, []byte(content))
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"datacenter":                 "dc1",
+		"enabled":                    "true",
+		"service/web/port":           "80",
+		"service/web/tags/0":         "public",
+		"service/web/tags/1":         "v2",
+		"service/web/check/interval": "10s",
+		"service/api/port":           "8080",
+		"limits/cpu":                 "500",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+}
+
+// TestHCLFileInvalid ensures syntax errors are reported.
+func TestHCLFileInvalid(t *testing.T) {
+	_, err := evaluate(&HCLFile{}, []byte("service \"web\" {\n  port = \n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// Create function creates the KV store entries based on the file content.
+func (f *INIFile) Create(kv Handler, repo repository.Repo) error {
+	return createEntries(kv, repo, f)
+}
+
+// Update functions updates the KV store based on the file content.
+func (f *INIFile) Update(kv Handler, repo repository.Repo) error {
+	return updateEntries(kv, repo, f)
+}
+
+// Delete removes the key-value pairs from the KV store under given prefix.
+func (f *INIFile) Delete(kv Handler, repo repository.Repo) error {
+	return deleteEntries(kv, repo, f)
+}
+
+// GetPath returns the path to the file.
+func (f *INIFile) GetPath() string {
+	return f.path
+}
+
+// Evaluates the INI file. The section name becomes the segment of the key
+// path, the keys defined before the first section are kept at the top level.
+func (f *INIFile) decode(content []byte) (interface{}, error) {
+	keys := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	section := keys
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNumber)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" {
+				return nil, fmt.Errorf("line %d: empty section name", lineNumber)
+			}
+			existing, found := keys[name]
+			if !found {
+				section = make(map[string]interface{})
+				keys[name] = section
+				continue
+			}
+			var ok bool
+			section, ok = existing.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("line %d: section [%s] collides with the top level key %q", lineNumber, name, name)
+			}
+			continue
+		}
+
+		key, value := line, ""
+		if i := strings.IndexAny(line, "=:"); i >= 0 {
+			key = strings.TrimSpace(line[:i])
+			value = unquote(strings.TrimSpace(line[i+1:]))
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", lineNumber)
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Removes the matching single or double quotes surrounding the value.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if first == last && (first == '"' || first == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
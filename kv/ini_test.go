@@ -0,0 +1,62 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestINIFileEntries verifies evaluation of the INI files.
+func TestINIFileEntries(t *testing.T) {
+	content := "; comment\n" +
+		"name = global\n" +
+		"\n" +
+		"[database]\n" +
+		"host = localhost\n" +
+		"port: 5432\n" +
+		"# comment\n" +
+		"password = \"se;cret\"\n" +
+		"[cache]\n" +
+		"enabled\n"
+	entries, err := evaluate(&INIFile{}, []byte(content))
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"name":              "global",
+		"database/host":     "localhost",
+		"database/port":     "5432",
+		"database/password": "se;cret",
+		"cache/enabled":     "",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+}
+
+// TestINIFileInvalidSection ensures malformed section headers are reported.
+func TestINIFileInvalidSection(t *testing.T) {
+	_, err := evaluate(&INIFile{}, []byte("[database\nhost = localhost\n"))
+	assert.EqualError(t, err, "line 1: unterminated section header")
+}
+
+// TestINIFileSectionCollision ensures a section named after a top level key is reported.
+func TestINIFileSectionCollision(t *testing.T) {
+	_, err := evaluate(&INIFile{}, []byte("name = top\n[name]\nhost = localhost\n"))
+	assert.EqualError(t, err, `line 2: section [name] collides with the top level key "name"`)
+}
@@ -74,7 +74,7 @@ func (h *KVHandler) handleRepoInit(repo repository.Repo) error {
 				h.putBranch(repo, plumbing.ReferenceName(ref.Name().Short())) //nolint:errcheck
 
 				h.logger.Infof("KV PUT ref: %s/%s", repo.Name(), ref.Name())
-				h.putKVRef(repo, ref.Name().String()) //nolint:errcheck
+				h.putKVRef(repo, ref) //nolint:errcheck
 			} else if kvRef != localRef {
 				// Check if the ref belongs to that repo
 				err := repo.CheckRef(kvRef)
@@ -89,7 +89,7 @@ func (h *KVHandler) handleRepoInit(repo repository.Repo) error {
 				}
 				h.handleDeltas(repo, deltas) //nolint:errcheck
 
-				err = h.putKVRef(repo, ref.Name().String())
+				err = h.putKVRef(repo, ref)
 				if err != nil {
 					return err
 				}
@@ -103,6 +103,12 @@ func (h *KVHandler) handleRepoInit(repo repository.Repo) error {
 
 // Helper function that handles deltas
 func (h *KVHandler) handleDeltas(repo repository.Repo, diff object.Changes) error {
+	// Handle every delta even if one of them fails, so all the failures are logged
+	var failed fileErrors
+	owners, err := newKeyOwners(repo)
+	if err != nil {
+		return err
+	}
 	for _, d := range diff {
 		action, err := d.Action()
 		if err != nil {
@@ -113,29 +119,20 @@ func (h *KVHandler) handleDeltas(repo repository.Repo, diff object.Changes) erro
 		case merkletrie.Insert:
 			filePath := filepath.Join(workDir, d.To.Name)
 			h.logger.Debugf("Detected added file: %s", filePath)
-			file := Init(filePath, repo)
-			err := file.Create(h, repo)
-			if err != nil {
-				return err
-			}
+			file := owners.init(filePath)
+			failed.add(h.logger, file.Create(h, repo))
 		case merkletrie.Modify:
 			filePath := filepath.Join(workDir, d.To.Name)
 			h.logger.Debugf("Detected modified file: %s", filePath)
-			file := Init(filePath, repo)
-			err := file.Update(h, repo)
-			if err != nil {
-				return err
-			}
+			file := owners.init(filePath)
+			failed.add(h.logger, file.Update(h, repo))
 		case merkletrie.Delete:
 			filePath := filepath.Join(workDir, d.From.Name)
 			h.logger.Debugf("Detected deleted file: %s", filePath)
-			file := Init(filePath, repo)
-			err := file.Delete(h, repo)
-			if err != nil {
-				return err
-			}
+			file := owners.init(filePath)
+			failed.add(h.logger, file.Delete(h, repo))
 		}
 	}
 
-	return nil
+	return failed.err()
 }
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// Create function creates the KV store entries based on the file content.
+func (f *JSONFile) Create(kv Handler, repo repository.Repo) error {
+	return createEntries(kv, repo, f)
+}
+
+func (f *JSONFile) decode(content []byte) (interface{}, error) {
+	var jsonTree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep the numbers as they are written in the file
+	decoder.UseNumber()
+	err := decoder.Decode(&jsonTree)
+	if err != nil {
+		return nil, err
+	}
+	return jsonTree, nil
+}
+
+// Update functions updates the KV store based on the file content.
+func (f *JSONFile) Update(kv Handler, repo repository.Repo) error {
+	return updateEntries(kv, repo, f)
+}
+
+// Delete removes the key-value pairs from the KV store under given prefix.
+func (f *JSONFile) Delete(kv Handler, repo repository.Repo) error {
+	return deleteEntries(kv, repo, f)
+}
+
+// GetPath returns the path to the file.
+func (f *JSONFile) GetPath() string {
+	return f.path
+}
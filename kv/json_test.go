@@ -0,0 +1,146 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/apex/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const jsonContent = `{
+  "service": {
+    "name": "web",
+    "port": 8080,
+    "ratio": 0.125,
+    "enabled": true,
+    "hosts": ["a.example.com", "b.example.com"],
+    "backends": [
+      {"name": "first", "weight": 10},
+      {"name": "second", "weight": 20}
+    ]
+  }
+}`
+
+// TestJSONFile verifies evaluation of the JSON files into key-value pairs.
+func TestJSONFile(t *testing.T) {
+	var repo repository.Repo = &mocks.Repo{Config: &config.Repo{}}
+	filePath := filepath.Join(t.TempDir(), "foo.json")
+	err := os.WriteFile(filePath, []byte(jsonContent), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonFile := &JSONFile{filePath}
+	handler := &mockHandler{t: t, filePath: filePath}
+	prefix := filepath.Join(filepath.Dir(filePath), "foo")
+
+	keys = make(map[string][]byte)
+	err = jsonFile.Create(handler, repo)
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"service/name":              "web",
+		"service/port":              "8080",
+		"service/ratio":             "0.125",
+		"service/enabled":           "true",
+		"service/hosts/0":           "a.example.com",
+		"service/hosts/1":           "b.example.com",
+		"service/backends/0/name":   "first",
+		"service/backends/0/weight": "10",
+		"service/backends/1/name":   "second",
+		"service/backends/1/weight": "20",
+	}
+	assert.Len(t, keys, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(keys[filepath.Join(prefix, key)]), key)
+	}
+
+	err = jsonFile.Delete(handler, repo)
+	assert.NoError(t, err)
+}
+
+// TestInitJSONFile ensures JSON files are expanded only when expand_keys is enabled.
+func TestInitJSONFile(t *testing.T) {
+	repo := &mocks.Repo{Config: &config.Repo{}}
+	assert.IsType(t, &TextFile{}, Init("foo.json", repo))
+
+	repo.Config.ExpandKeys = true
+	assert.IsType(t, &JSONFile{}, Init("foo.json", repo))
+	assert.IsType(t, &YAMLFile{}, Init("foo.yml", repo))
+	assert.IsType(t, &TextFile{}, Init("foo.txt", repo))
+}
+
+// TestDeleteJSONFileSiblings ensures the deletion of an expanded file keeps
+// the keys of the files whose names share its prefix.
+func TestDeleteJSONFileSiblings(t *testing.T) {
+	repoPath := t.TempDir()
+	files := map[string]string{
+		"app.json":             `{"port": 8080}`,
+		"version.json":         `"1.2.3"`,
+		"apple.txt":            "apple",
+		"app-old.txt":          "old",
+		"application/name.txt": "name",
+		"version-old.txt":      "old",
+	}
+	for name, content := range files {
+		err := os.MkdirAll(filepath.Dir(filepath.Join(repoPath, name)), 0o700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{ExpandKeys: true}}
+	kv := &mocks.KV{T: t}
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+
+	assert.NoError(t, handler.putBranch(repo, repo.Branch()))
+	assert.NoError(t, handler.Commit())
+	pair, _, _ := kv.Get("repository_mock/master/version", nil)
+	if assert.NotNil(t, pair) {
+		assert.Equal(t, "1.2.3", string(pair.Value))
+	}
+
+	for _, name := range []string{"app.json", "version.json"} {
+		assert.NoError(t, os.Remove(filepath.Join(repoPath, name)))
+		assert.NoError(t, Init(filepath.Join(repoPath, name), repo).Delete(handler, repo))
+	}
+	assert.NoError(t, handler.Commit())
+
+	for _, key := range []string{"app/port", "version"} {
+		pair, _, _ = kv.Get("repository_mock/master/"+key, nil)
+		assert.Nil(t, pair, key)
+	}
+	for _, key := range []string{"apple.txt", "app-old.txt", "application/name.txt", "version-old.txt"} {
+		pair, _, _ = kv.Get("repository_mock/master/"+key, nil)
+		if assert.NotNil(t, pair, key) {
+			assert.Equal(t, files[key], string(pair.Value))
+		}
+	}
+}
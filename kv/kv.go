@@ -17,12 +17,20 @@ limitations under the License.
 package kv
 
 import (
+	"bytes"
+	"strings"
+
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/hashicorp/consul/api"
 )
 
 // PutKV triggers an KV api request to put data to the Consul.
 func (h *KVHandler) PutKV(repo repository.Repo, prefix string, value []byte) error {
+	return h.PutKVFlags(repo, prefix, value, 0)
+}
+
+// PutKVFlags puts the value annotated with the flags to the KV store.
+func (h *KVHandler) PutKVFlags(repo repository.Repo, prefix string, value []byte, flags uint64) error {
 	head, err := repo.Head()
 	if err != nil {
 		return err
@@ -41,11 +49,21 @@ func (h *KVHandler) PutKV(repo repository.Repo, prefix string, value []byte) err
 		return nil
 	}
 
+	current, exists, err := h.currentValue(key)
+	if err != nil {
+		return err
+	}
+	if exists && bytes.Equal(current, value) {
+		h.logger.Debugf("KV unchanged: %s/%s: %s", repo.Name(), branchName, key)
+		return nil
+	}
+
 	h.logger.Debugf("KV PUT: %s/%s: %s", repo.Name(), branchName, key)
 
 	p := &api.KVPair{
 		Key:   key,
 		Value: value,
+		Flags: flags,
 	}
 
 	_, err = h.Put(p, nil)
@@ -78,7 +96,9 @@ func (h *KVHandler) DeleteKV(repo repository.Repo, prefix string) error {
 	return nil
 }
 
-// DeleteTreeKV deletes recursively all the keys with given prefix.
+// DeleteTreeKV deletes recursively all the keys under the given prefix. The
+// prefix is treated as a directory, so the keys which only share its leading
+// characters are kept.
 func (h *KVHandler) DeleteTreeKV(repo repository.Repo, prefix string) error {
 	key, status, err := getItemKey(repo, prefix)
 	if err != nil {
@@ -91,6 +111,7 @@ func (h *KVHandler) DeleteTreeKV(repo repository.Repo, prefix string) error {
 		return nil
 	}
 
+	key += "/"
 	h.logger.Infof("KV DEL %s/%s/%s", repo.Name(), repo.Branch(), key)
 	_, err = h.DeleteTree(key, nil)
 	if err != nil {
@@ -99,3 +120,25 @@ func (h *KVHandler) DeleteTreeKV(repo repository.Repo, prefix string) error {
 
 	return nil
 }
+
+// ListKV returns the keys stored under the given prefix. The keys are
+// relative to the prefix.
+func (h *KVHandler) ListKV(repo repository.Repo, prefix string) ([]string, error) {
+	key, status, err := getItemKey(repo, prefix)
+	if err != nil {
+		if status == PathFormatterError {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	keys, err := h.currentKeys(key + "/")
+	if err != nil {
+		return nil, err
+	}
+	relative := make([]string, 0, len(keys))
+	for _, k := range keys {
+		relative = append(relative, strings.TrimPrefix(k, key+"/"))
+	}
+	return relative, nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/apex/log"
+)
+
+// Flags annotating the values of the KV pairs.
+const (
+	// FlagBase64 marks the values holding base64 encoded binary content.
+	FlagBase64 uint64 = 1 << iota
+	// FlagManifest marks the manifest of the file split into chunks.
+	FlagManifest
+)
+
+// chunkSize is the maximum size of a chunk, small enough for the chunk to fit
+// into a transaction once its value is base64 encoded in the request body.
+const chunkSize = 256 * 1024
+
+// chunksDir is the path under the file key where the chunks are stored.
+const chunksDir = "chunks"
+
+// Manifest describes the file which was split into chunks. It is stored
+// under the key of the file while the chunks are stored under the
+// <key>/chunks/<index> keys. The concatenated chunks form the stored value.
+type Manifest struct {
+	Chunks   int    `json:"chunks"`
+	Size     int    `json:"size"`
+	SHA256   string `json:"sha256"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Returns true if the content is not a valid UTF-8 text.
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// Returns the value stored for the file content along with its flags.
+func encodeContent(cfg *config.Repo, content []byte) ([]byte, uint64) {
+	if cfg.BinaryEncoding == config.BinaryEncodingBase64 && isBinary(content) {
+		value := make([]byte, base64.StdEncoding.EncodedLen(len(content)))
+		base64.StdEncoding.Encode(value, content)
+		return value, FlagBase64
+	}
+	return content, 0
+}
+
+// Puts the file content to the KV, applying the large_files policy when
+// the value exceeds the max_value_size.
+func putFileContent(kv Handler, repo repository.Repo, path string, content []byte) error {
+	cfg := repo.GetConfig()
+	value, flags := encodeContent(cfg, content)
+	limit := cfg.MaxValueSize
+	if limit == 0 {
+		limit = config.DefaultMaxValueSize
+	}
+
+	chunked := cfg.LargeFiles == config.LargeFilesChunk && len(value) > limit
+	if cfg.LargeFiles == config.LargeFilesChunk {
+		err := deleteStaleChunks(kv, repo, path, value, limit, chunked)
+		if err != nil {
+			return err
+		}
+	}
+	if len(value) <= limit {
+		return kv.PutKVFlags(repo, path, value, flags)
+	}
+
+	switch cfg.LargeFiles {
+	case config.LargeFilesSkip:
+		log.Warnf("Skipping %s, the size of %d bytes exceeds the %d bytes limit", path, len(value), limit)
+		return nil
+	case config.LargeFilesChunk:
+		return putChunks(kv, repo, path, value, flags, limit)
+	default:
+		return fmt.Errorf("%s size of %d bytes exceeds the %d bytes limit, see the large_files option", path, len(value), limit)
+	}
+}
+
+// Splits the value into chunks and puts them along with the manifest.
+func putChunks(kv Handler, repo repository.Repo, path string, value []byte, flags uint64, limit int) error {
+	size := chunkLength(limit)
+	sum := sha256.Sum256(value)
+	manifest := Manifest{
+		Chunks: (len(value) + size - 1) / size,
+		Size:   len(value),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	if flags&FlagBase64 != 0 {
+		manifest.Encoding = config.BinaryEncodingBase64
+	}
+	for i := 0; i < manifest.Chunks; i++ {
+		end := (i + 1) * size
+		if end > len(value) {
+			end = len(value)
+		}
+		err := kv.PutKVFlags(repo, chunkPath(path, i), value[i*size:end], flags)
+		if err != nil {
+			return err
+		}
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return kv.PutKVFlags(repo, path, content, FlagManifest)
+}
+
+// Deletes the chunks which are not going to be overwritten by the new value.
+func deleteStaleChunks(kv Handler, repo repository.Repo, path string, value []byte, limit int, chunked bool) error {
+	chunks := 0
+	if chunked {
+		size := chunkLength(limit)
+		chunks = (len(value) + size - 1) / size
+	}
+	existing, err := kv.ListKV(repo, filepath.Join(path, chunksDir))
+	if err != nil {
+		return err
+	}
+	for _, key := range existing {
+		index, err := strconv.Atoi(key)
+		if err == nil && index < chunks {
+			continue
+		}
+		err = kv.DeleteKV(repo, filepath.Join(path, chunksDir, key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chunkLength(limit int) int {
+	if limit < chunkSize {
+		return limit
+	}
+	return chunkSize
+}
+
+func chunkPath(path string, index int) string {
+	return filepath.Join(path, chunksDir, strconv.Itoa(index))
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/apex/log"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLargeFileTest(t *testing.T, cfg *config.Repo) (*KVHandler, *mocks.KV, *mocks.Repo) {
+	kv := &mocks.KV{T: t}
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	return handler, kv, &mocks.Repo{Path: t.TempDir(), Config: cfg, T: t}
+}
+
+func writeFile(t *testing.T, repo *mocks.Repo, name string, content []byte) File {
+	filePath := filepath.Join(repo.Path, name)
+	err := os.WriteFile(filePath, content, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Init(filePath, repo)
+}
+
+// TestBinaryEncoding verifies the binary content is base64 encoded and flagged.
+func TestBinaryEncoding(t *testing.T) {
+	handler, _, repo := newLargeFileTest(t, &config.Repo{BinaryEncoding: config.BinaryEncodingBase64})
+
+	err := writeFile(t, repo, "cert.der", []byte{0x30, 0x82, 0x00, 0xff}).Create(handler, repo)
+	assert.NoError(t, err)
+	err = writeFile(t, repo, "text.txt", []byte("plain text")).Create(handler, repo)
+	assert.NoError(t, err)
+
+	if assert.Len(t, handler.KVTxnOps, 2) {
+		assert.Equal(t, "MIIA/w==", string(handler.KVTxnOps[0].Value))
+		assert.Equal(t, FlagBase64, handler.KVTxnOps[0].Flags)
+		assert.Equal(t, "plain text", string(handler.KVTxnOps[1].Value))
+		assert.Equal(t, uint64(0), handler.KVTxnOps[1].Flags)
+	}
+}
+
+// TestLargeFilesPolicy verifies the fail and skip policies of the oversized files.
+func TestLargeFilesPolicy(t *testing.T) {
+	handler, _, repo := newLargeFileTest(t, &config.Repo{MaxValueSize: 10, LargeFiles: config.LargeFilesFail})
+	file := writeFile(t, repo, "large.txt", []byte("more than ten bytes"))
+	assert.Error(t, file.Create(handler, repo))
+
+	repo.Config.LargeFiles = config.LargeFilesSkip
+	assert.NoError(t, file.Create(handler, repo))
+	assert.Empty(t, handler.KVTxnOps)
+}
+
+// TestLargeFilesChunk verifies the oversized files are split into chunks.
+func TestLargeFilesChunk(t *testing.T) {
+	handler, kv, repo := newLargeFileTest(t, &config.Repo{MaxValueSize: 10, LargeFiles: config.LargeFilesChunk})
+	content := strings.Repeat("a", 10) + strings.Repeat("b", 10) + "c"
+	file := writeFile(t, repo, "large.txt", []byte(content))
+	assert.NoError(t, file.Create(handler, repo))
+	assert.NoError(t, handler.Commit())
+
+	pair, _, _ := kv.Get("repository_mock/master/large.txt", nil)
+	if assert.NotNil(t, pair) {
+		var manifest Manifest
+		assert.NoError(t, json.Unmarshal(pair.Value, &manifest))
+		assert.Equal(t, 3, manifest.Chunks)
+		assert.Equal(t, len(content), manifest.Size)
+	}
+	pairs, _, _ := kv.List("repository_mock/master/large.txt/chunks/", nil)
+	if assert.Len(t, pairs, 3) {
+		assert.Equal(t, "aaaaaaaaaa", string(pairs[0].Value))
+		assert.Equal(t, "c", string(pairs[2].Value))
+	}
+
+	// The chunks are removed once the file fits into a single value
+	file = writeFile(t, repo, "large.txt", []byte("small"))
+	assert.NoError(t, file.Update(handler, repo))
+	assert.NoError(t, handler.Commit())
+	pair, _, _ = kv.Get("repository_mock/master/large.txt", nil)
+	assert.Equal(t, "small", string(pair.Value))
+	pairs, _, _ = kv.List("repository_mock/master/large.txt/chunks/", nil)
+	assert.Empty(t, pairs)
+}
+
+// TestSplitIntoSlices verifies the transactions are limited by the number of items and their size.
+func TestSplitIntoSlices(t *testing.T) {
+	handler := &KVHandler{}
+	large := make([]byte, consulTxnBytes/2+1)
+	ops := api.KVTxnOps{
+		{Verb: api.KVSet, Key: "a", Value: large},
+		{Verb: api.KVSet, Key: "b", Value: large},
+		{Verb: api.KVSet, Key: "c", Value: []byte("c")},
+		{Verb: api.KVCheckIndex, Key: "ref"},
+		{Verb: api.KVSet, Key: "ref", Value: []byte("ref")},
+	}
+	slices := handler.splitIntoSlices(ops, consulTxnSize)
+	if assert.Len(t, slices, 2) {
+		assert.Len(t, slices[0], 1)
+		assert.Len(t, slices[1], 4)
+	}
+
+	// The modify index check is not separated from the item it guards
+	slices = handler.splitIntoSlices(ops[2:], 2)
+	if assert.Len(t, slices, 2) {
+		assert.Len(t, slices[0], 1)
+		assert.Len(t, slices[1], 2)
+	}
+}
@@ -18,6 +18,8 @@ package mocks
 
 import (
 	"math/rand"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/apex/log"
@@ -45,6 +47,19 @@ func (kv *KV) Get(key string, opts *api.QueryOptions) (*api.KVPair, *api.QueryMe
 	return nil, nil, nil
 }
 
+// List returns all the items with given prefix
+func (kv *KV) List(prefix string, opts *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	kv.T.Logf("KV List %s", prefix)
+	var pairs api.KVPairs
+	for key, val := range kv.items {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, &api.KVPair{Key: key, Value: val.value, ModifyIndex: val.modifyindex})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil, nil
+}
+
 // Put TODO write a useful documentation here
 func (kv *KV) Put(kvPair *api.KVPair, wOptions *api.WriteOptions) (*api.WriteMeta, error) {
 	if kv.items == nil {
@@ -67,22 +82,42 @@ func (kv *KV) Txn(txnops api.KVTxnOps, opts *api.QueryOptions) (bool, *api.KVTxn
 	if length := len(txnops); length > 1 && txnops[length-2].Verb == api.KVCheckIndex {
 		checkIndexItem = txnops[length-2]
 	}
+	// The transaction is rejected as a whole, before any item is applied
+	if checkIndexItem != nil && kv.index(checkIndexItem.Key) != checkIndexItem.Index {
+		return false, &api.KVTxnResponse{}, nil, nil
+	}
+	for _, item := range txnops {
+		if (item.Verb == api.KVCAS || item.Verb == api.KVDeleteCAS) && kv.index(item.Key) != item.Index {
+			return false, &api.KVTxnResponse{Errors: api.TxnErrors{{What: "failed to set key " + item.Key}}}, nil, nil
+		}
+	}
+	response := &api.KVTxnResponse{}
 	for _, item := range txnops {
 		// nolint: exhaustive
 		switch item.Verb {
-		case api.KVSet:
-			if checkIndexItem != nil && item.Key == checkIndexItem.Key {
-				kvPair, _, _ := kv.Get(item.Key, nil)
-				if kvPair.ModifyIndex != checkIndexItem.Index {
-					return false, &api.KVTxnResponse{}, nil, nil
-				}
-			}
+		case api.KVSet, api.KVCAS:
 			kv.Put(&api.KVPair{Key: item.Key, Value: item.Value}, nil) //nolint:errcheck
-		case api.KVDelete:
+			response.Results = append(response.Results, &api.KVPair{Key: item.Key, ModifyIndex: kv.index(item.Key)})
+		case api.KVDelete, api.KVDeleteCAS:
 			kv.Delete(item.Key, nil) //nolint:errcheck
+		case api.KVDeleteTree:
+			for key := range kv.items {
+				if strings.HasPrefix(key, item.Key) {
+					kv.Delete(key, nil) //nolint:errcheck
+				}
+			}
+		case api.KVCheckIndex:
 		default:
 			log.WithField("KVOp", item.Verb).Error("unhandled consul KVOp")
 		}
 	}
-	return true, nil, nil, nil
+	return true, response, nil, nil
+}
+
+// Returns the modify index of the key, zero if it does not exist.
+func (kv *KV) index(key string) uint64 {
+	if val, ok := kv.items[key]; ok {
+		return val.modifyindex
+	}
+	return 0
 }
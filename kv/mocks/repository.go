@@ -123,3 +123,17 @@ func (r *Repo) GetStorer() storage.Storer {
 func (r *Repo) Branch() plumbing.ReferenceName {
 	return r.branch
 }
+
+// TrackedRefs TODO write a useful documentation here
+func (r *Repo) TrackedRefs() ([]plumbing.ReferenceName, error) {
+	var refs []plumbing.ReferenceName
+	for _, branch := range r.Config.Branches {
+		refs = append(refs, plumbing.NewBranchReferenceName(branch))
+	}
+	return refs, nil
+}
+
+// FetchRefs TODO write a useful documentation here
+func (r *Repo) FetchRefs() ([]plumbing.ReferenceName, error) {
+	return nil, nil
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package kv
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/KohlsTechnology/git2consul-go/repository"
@@ -85,6 +88,99 @@ func pathCoreBuilder(repo repository.Repo, filePath string) (string, int, error)
 	return filePath, PathFormatterOK, nil
 }
 
+// Returns the file path stripped of its extension when the strip_extensions
+// option of the repository applies to the file. Dotfiles keep their name.
+func stripExtension(repo repository.Repo, filePath string) string {
+	strip := repo.GetConfig().StripExtensions
+	ext := filepath.Ext(filePath)
+	if !strip.Enabled || ext == "" || ext == filepath.Base(filePath) {
+		return filePath
+	}
+	if len(strip.Extensions) == 0 {
+		return strings.TrimSuffix(filePath, ext)
+	}
+	for _, e := range strip.Extensions {
+		if ext == "."+strings.TrimPrefix(e, ".") {
+			return strings.TrimSuffix(filePath, ext)
+		}
+	}
+	return filePath
+}
+
+// keyOwners resolves the text files owning the keys the files map to once
+// their extension is stripped. Every directory is read once, so a walk of the
+// branch does not read the directory again for each of its files.
+type keyOwners struct {
+	repo   repository.Repo
+	filter *repository.Filter
+	// owners by the stripped path, by directory
+	dirs map[string]map[string]string
+}
+
+func newKeyOwners(repo repository.Repo) (*keyOwners, error) {
+	filter, err := repository.NewFilter(repo)
+	if err != nil {
+		return nil, err
+	}
+	return &keyOwners{repo: repo, filter: filter, dirs: make(map[string]map[string]string)}, nil
+}
+
+// Returns the file handler of the path, the text files resolve the owners of
+// their key with the receiver.
+func (o *keyOwners) init(filePath string) File {
+	file := Init(filePath, o.repo)
+	if text, ok := file.(*TextFile); ok {
+		text.owners = o
+	}
+	return file
+}
+
+// Returns the text file owning the key the file maps to once its extension
+// is stripped. When multiple files of the directory map to the same key, the
+// first one by name owns the key. An empty path is returned when no file
+// currently maps to the key.
+func (o *keyOwners) owner(filePath string) (string, error) {
+	dir := filepath.Dir(filePath)
+	owners, ok := o.dirs[dir]
+	if !ok {
+		var err error
+		owners, err = o.readDir(dir)
+		if err != nil {
+			return "", err
+		}
+		o.dirs[dir] = owners
+	}
+	return owners[stripExtension(o.repo, filePath)], nil
+}
+
+// Returns the owners of the keys of the text files of the directory.
+func (o *keyOwners) readDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	owners := make(map[string]string)
+	// The entries are sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		sibling := filepath.Join(dir, entry.Name())
+		keyPath := stripExtension(o.repo, sibling)
+		if _, ok := owners[keyPath]; ok {
+			continue
+		}
+		name, err := filepath.Rel(repository.WorkDir(o.repo), sibling)
+		if err != nil || !o.filter.Match(filepath.ToSlash(name)) {
+			continue
+		}
+		if _, ok := Init(sibling, o.repo).(*TextFile); ok {
+			owners[keyPath] = sibling
+		}
+	}
+	return owners, nil
+}
+
 func getBranchName(repo repository.Repo) (string, error) {
 	config := repo.GetConfig()
 	if config.SkipBranchName {
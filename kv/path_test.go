@@ -17,11 +17,14 @@ limitations under the License.
 package kv
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/KohlsTechnology/git2consul-go/config"
 	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
 	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/apex/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -163,3 +166,87 @@ func TestPathBaseBuilderWithRepo(t *testing.T) {
 	assert.Equal(t, key, expectedKey)
 	assert.Equal(t, status, PathFormatterOK)
 }
+
+func TestStripExtension(t *testing.T) {
+	var repo repository.Repo = &mocks.Repo{Config: &config.Repo{}}
+	assert.Equal(t, "app/db.conf", stripExtension(repo, "app/db.conf"))
+
+	repo.GetConfig().StripExtensions = config.StripExtensions{Enabled: true}
+	assert.Equal(t, "app/db", stripExtension(repo, "app/db.conf"))
+	assert.Equal(t, "app/db.conf", stripExtension(repo, "app/db.conf.tmpl"))
+	assert.Equal(t, "app/.env", stripExtension(repo, "app/.env"))
+	assert.Equal(t, "app/README", stripExtension(repo, "app/README"))
+
+	repo.GetConfig().StripExtensions = config.StripExtensions{Enabled: true, Extensions: []string{"conf", ".txt"}}
+	assert.Equal(t, "app/db", stripExtension(repo, "app/db.conf"))
+	assert.Equal(t, "app/notes", stripExtension(repo, "app/notes.txt"))
+	assert.Equal(t, "app/cert.pem", stripExtension(repo, "app/cert.pem"))
+}
+
+// TestStripExtensionCollision verifies the first file by name owns the key the files map to.
+func TestStripExtensionCollision(t *testing.T) {
+	repoPath := t.TempDir()
+	for _, name := range []string{"db.conf", "db.txt"} {
+		err := os.WriteFile(filepath.Join(repoPath, name), []byte(name), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{StripExtensions: config.StripExtensions{Enabled: true}}}
+	kv := &mocks.KV{T: t}
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+
+	assert.NoError(t, handler.putBranch(repo, repo.Branch()))
+	assert.NoError(t, handler.Commit())
+	pair, _, _ := kv.Get("repository_mock/master/db", nil)
+	if assert.NotNil(t, pair) {
+		assert.Equal(t, "db.conf", string(pair.Value))
+	}
+
+	// The remaining file takes over the key once the owner is deleted
+	assert.NoError(t, os.Remove(filepath.Join(repoPath, "db.conf")))
+	assert.NoError(t, Init(filepath.Join(repoPath, "db.conf"), repo).Delete(handler, repo))
+	assert.NoError(t, handler.Commit())
+	pair, _, _ = kv.Get("repository_mock/master/db", nil)
+	if assert.NotNil(t, pair) {
+		assert.Equal(t, "db.txt", string(pair.Value))
+	}
+
+	assert.NoError(t, os.Remove(filepath.Join(repoPath, "db.txt")))
+	assert.NoError(t, Init(filepath.Join(repoPath, "db.txt"), repo).Delete(handler, repo))
+	assert.NoError(t, handler.Commit())
+	pair, _, _ = kv.Get("repository_mock/master/db", nil)
+	assert.Nil(t, pair)
+}
+
+// TestKeyOwners verifies the owners of the keys are resolved once per directory.
+func TestKeyOwners(t *testing.T) {
+	repoPath := t.TempDir()
+	for _, name := range []string{"db.conf", "db.txt", "app.yml", "cache.txt"} {
+		err := os.WriteFile(filepath.Join(repoPath, name), []byte(name), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{ExpandKeys: true, StripExtensions: config.StripExtensions{Enabled: true}}}
+	owners, err := newKeyOwners(repo)
+	assert.NoError(t, err)
+
+	owner, err := owners.owner(filepath.Join(repoPath, "db.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(repoPath, "db.conf"), owner)
+	// The directory is not read again
+	assert.NoError(t, os.Remove(filepath.Join(repoPath, "cache.txt")))
+	owner, err = owners.owner(filepath.Join(repoPath, "cache.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(repoPath, "cache.txt"), owner)
+	// The YAML files own no key of a text file
+	owner, err = owners.owner(filepath.Join(repoPath, "app.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", owner)
+}
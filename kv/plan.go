@@ -0,0 +1,190 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Actions of the planned changes
+const (
+	PlanAdd    = "add"
+	PlanModify = "modify"
+	PlanDelete = "delete"
+)
+
+// PlanChange is a single change the sync would apply to the KV.
+type PlanChange struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+type planItem struct {
+	value  []byte
+	exists bool
+}
+
+// Plan collects the operations of the transactions instead of sending them
+// to the KV and compares them against the current state of the KV.
+type Plan struct {
+	api      API
+	original map[string]*planItem
+	planned  map[string]*planItem
+}
+
+// DryRun switches the handler to the dry-run mode. The transactions are not
+// sent to the KV anymore, but recorded in the returned plan.
+func (h *KVHandler) DryRun() *Plan {
+	h.plan = &Plan{
+		api:      h.API,
+		original: make(map[string]*planItem),
+		planned:  make(map[string]*planItem),
+	}
+	return h.plan
+}
+
+// Loads the current value of the key from the KV, unless it's already known.
+func (p *Plan) load(key string) error {
+	if _, ok := p.original[key]; ok {
+		return nil
+	}
+	pair, _, err := p.api.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	item := &planItem{}
+	if pair != nil {
+		item = &planItem{value: pair.Value, exists: true}
+	}
+	p.original[key] = item
+	p.planned[key] = item
+	return nil
+}
+
+// Applies the transaction to the planned state of the KV.
+func (p *Plan) apply(kvTxnOps api.KVTxnOps) error {
+	for _, op := range kvTxnOps {
+		// nolint: exhaustive
+		switch op.Verb {
+		case api.KVSet:
+			if err := p.load(op.Key); err != nil {
+				return err
+			}
+			p.planned[op.Key] = &planItem{value: op.Value, exists: true}
+		case api.KVDelete:
+			if err := p.load(op.Key); err != nil {
+				return err
+			}
+			p.planned[op.Key] = &planItem{}
+		case api.KVDeleteTree:
+			pairs, _, err := p.api.List(op.Key, nil)
+			if err != nil {
+				return err
+			}
+			for _, pair := range pairs {
+				if _, ok := p.original[pair.Key]; !ok {
+					item := &planItem{value: pair.Value, exists: true}
+					p.original[pair.Key] = item
+				}
+			}
+			for key := range p.original {
+				if strings.HasPrefix(key, op.Key) {
+					p.planned[key] = &planItem{}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Changes returns the changes between the current and the planned state of
+// the KV, sorted by the key.
+func (p *Plan) Changes() []*PlanChange {
+	changes := []*PlanChange{}
+	for key, original := range p.original {
+		planned := p.planned[key]
+		change := &PlanChange{Key: key, Old: string(original.value), New: string(planned.value)}
+		switch {
+		case !original.exists && planned.exists:
+			change.Action = PlanAdd
+		case original.exists && !planned.exists:
+			change.Action = PlanDelete
+		case original.exists && planned.exists && change.Old != change.New:
+			change.Action = PlanModify
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// WriteText writes the human-readable summary of the changes.
+func (p *Plan) WriteText(w io.Writer) error {
+	changes := p.Changes()
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Action]++
+		var line string
+		switch change.Action {
+		case PlanAdd:
+			line = fmt.Sprintf("+ %s = %s\n", change.Key, displayValue(change.New))
+		case PlanModify:
+			line = fmt.Sprintf("~ %s: %s -> %s\n", change.Key, displayValue(change.Old), displayValue(change.New))
+		case PlanDelete:
+			line = fmt.Sprintf("- %s\n", change.Key)
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	if len(changes) == 0 {
+		_, err := io.WriteString(w, "No changes. The KV is up to date.\n")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Plan: %d to add, %d to change, %d to delete.\n",
+		counts[PlanAdd], counts[PlanModify], counts[PlanDelete])
+	return err
+}
+
+// WriteJSON writes the changes as JSON document.
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Changes []*PlanChange `json:"changes"`
+	}{p.Changes()})
+}
+
+// Shortens the values which are not suitable for a single line of the summary.
+func displayValue(value string) string {
+	const maxLength = 64
+	if !utf8.ValidString(value) || strings.ContainsAny(value, "\r\n") || len(value) > maxLength {
+		return fmt.Sprintf("(%d bytes)", len(value))
+	}
+	return fmt.Sprintf("%q", value)
+}
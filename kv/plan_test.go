@@ -0,0 +1,92 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/apex/log"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPlan verifies the dry-run mode records the changes without writing them.
+func TestPlan(t *testing.T) {
+	kv := &mocks.KV{T: t}
+	kv.Put(&api.KVPair{Key: "repo/main/same", Value: []byte("same")}, nil)          //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/changed", Value: []byte("old")}, nil)        //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/removed", Value: []byte("removed")}, nil)    //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/tree/first", Value: []byte("first")}, nil)   //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/tree/second", Value: []byte("second")}, nil) //nolint:errcheck
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	plan := handler.DryRun()
+
+	handler.Put(&api.KVPair{Key: "repo/main/same", Value: []byte("same")}, nil)        //nolint:errcheck
+	handler.Put(&api.KVPair{Key: "repo/main/changed", Value: []byte("new")}, nil)      //nolint:errcheck
+	handler.Put(&api.KVPair{Key: "repo/main/added", Value: []byte("added")}, nil)      //nolint:errcheck
+	handler.Delete("repo/main/removed", nil)                                           //nolint:errcheck
+	handler.DeleteTree("repo/main/tree", nil)                                          //nolint:errcheck
+	handler.Put(&api.KVPair{Key: "repo/main/tree/first", Value: []byte("first")}, nil) //nolint:errcheck
+	err := handler.Commit()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*PlanChange{
+		{Action: PlanAdd, Key: "repo/main/added", New: "added"},
+		{Action: PlanModify, Key: "repo/main/changed", Old: "old", New: "new"},
+		{Action: PlanDelete, Key: "repo/main/removed", Old: "removed"},
+		{Action: PlanDelete, Key: "repo/main/tree/second", Old: "second"},
+	}, plan.Changes())
+
+	// Nothing is written to the KV
+	pair, _, _ := kv.Get("repo/main/added", nil)
+	assert.Nil(t, pair)
+	pair, _, _ = kv.Get("repo/main/changed", nil)
+	assert.Equal(t, "old", string(pair.Value))
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, plan.WriteText(out))
+	assert.Equal(t, "+ repo/main/added = \"added\"\n"+
+		"~ repo/main/changed: \"old\" -> \"new\"\n"+
+		"- repo/main/removed\n"+
+		"- repo/main/tree/second\n"+
+		"Plan: 1 to add, 1 to change, 2 to delete.\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, plan.WriteJSON(out))
+	var document struct {
+		Changes []*PlanChange `json:"changes"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &document))
+	assert.Equal(t, plan.Changes(), document.Changes)
+}
+
+// TestPlanNoChanges verifies the summary of the empty plan.
+func TestPlanNoChanges(t *testing.T) {
+	handler := &KVHandler{API: &mocks.KV{T: t}}
+	plan := handler.DryRun()
+	out := &bytes.Buffer{}
+	assert.NoError(t, plan.WriteText(out))
+	assert.Equal(t, "No changes. The KV is up to date.\n", out.String())
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPropertiesFileEntries verifies evaluation of the Java properties files.
+func TestPropertiesFileEntries(t *testing.T) {
+	content := "# comment\n" +
+		"! another comment\n" +
+		"db.host=localhost\n" +
+		"db.port : 5432\n" +
+		"db.name   orders\n" +
+		"empty=\n" +
+		"multi.line = first, \\\n" +
+		"    second\n" +
+		"escaped\\ key=tab\\there\n" +
+		"unicode=caf\\u00e9\n"
+	entries, err := evaluate(&PropertiesFile{}, []byte(content))
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"db.host":     "localhost",
+		"db.port":     "5432",
+		"db.name":     "orders",
+		"empty":       "",
+		"multi.line":  "first, second",
+		"escaped key": "tab\there",
+		"unicode":     "café",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+}
+
+// TestPropertiesFileInvalidEscape ensures malformed unicode escapes are reported.
+func TestPropertiesFileInvalidEscape(t *testing.T) {
+	_, err := evaluate(&PropertiesFile{}, []byte("key=\\u12"))
+	assert.Error(t, err)
+}
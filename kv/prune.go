@@ -0,0 +1,154 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/hashicorp/consul/api"
+)
+
+// keyCollector is a Handler which records the keys the files would be
+// pushed to, without touching the KV.
+type keyCollector struct {
+	keys map[string]bool
+}
+
+// PutKV records the key of the item.
+func (c *keyCollector) PutKV(repo repository.Repo, prefix string, value []byte) error {
+	key, status, err := getItemKey(repo, prefix)
+	if err != nil {
+		if status == PathFormatterError {
+			return err
+		}
+		return nil
+	}
+	c.keys[key] = true
+	return nil
+}
+
+// PutKVFlags records the key of the item.
+func (c *keyCollector) PutKVFlags(repo repository.Repo, prefix string, value []byte, flags uint64) error {
+	return c.PutKV(repo, prefix, value)
+}
+
+// DeleteKV is a no-op, the collector records the pushed keys only.
+func (c *keyCollector) DeleteKV(repository.Repo, string) error { return nil }
+
+// DeleteTreeKV is a no-op, the collector records the pushed keys only.
+func (c *keyCollector) DeleteTreeKV(repository.Repo, string) error { return nil }
+
+// ListKV returns no keys, the collector records the pushed keys only.
+func (c *keyCollector) ListKV(repository.Repo, string) ([]string, error) { return nil, nil }
+
+// HandleUpdate is a no-op, the collector records the pushed keys only.
+func (c *keyCollector) HandleUpdate(repository.Repo) error { return nil }
+
+// ForceUpdate is a no-op, the collector records the pushed keys only.
+func (c *keyCollector) ForceUpdate(repository.Repo) error { return nil }
+
+// AppliedOps returns zero, the collector records the pushed keys only.
+func (c *keyCollector) AppliedOps() int { return 0 }
+
+// Returns the keys the files of the checked out branch are pushed to.
+func branchKeys(repo repository.Repo) (map[string]bool, error) {
+	collector := &keyCollector{keys: make(map[string]bool)}
+	err := walkBranch(repo, func(file File) error {
+		return file.Create(collector, repo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collector.keys, nil
+}
+
+// Deletes the keys under the prefix of the checked out branch which are not
+// produced by any file of the branch. The prefix used by the previous sync is
+// pruned as well, so no keys are left behind when the mount_point changes.
+func (h *KVHandler) pruneBranch(repo repository.Repo) error {
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	prefix, _, err := pathBaseBuilder(repo)
+	if err != nil {
+		return err
+	}
+	if prefix == "" {
+		return fmt.Errorf("refusing to prune %s/%s: the keys are not prefixed", repo.Name(), head.Name().Short())
+	}
+
+	expected, err := branchKeys(repo)
+	if err != nil {
+		return err
+	}
+
+	prefixes := []string{prefix}
+	prefixKey := path.Join(repo.Name(), fmt.Sprintf("%s.prefix", head.Name().Short()))
+	pair, _, err := h.Get(prefixKey, nil)
+	if err != nil {
+		return err
+	}
+	if pair != nil && len(pair.Value) > 0 && string(pair.Value) != prefix {
+		prefixes = append(prefixes, string(pair.Value))
+	}
+
+	for _, prefix := range prefixes {
+		pairs, _, err := h.List(prefix+"/", nil)
+		if err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			if expected[pair.Key] || isMetadataKey(repo, pair.Key) {
+				continue
+			}
+			h.logger.Infof("KV PRUNE %s/%s: %s", repo.Name(), head.Name().Short(), pair.Key)
+			_, err = h.Delete(pair.Key, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if pair == nil || string(pair.Value) != prefix {
+		_, err = h.Put(&api.KVPair{Key: prefixKey, Value: []byte(prefix)}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks whether the key is the ref or the prefix key git2consul stores for
+// one of the branches or tags tracked by the repository.
+func isMetadataKey(repo repository.Repo, key string) bool {
+	name := strings.TrimPrefix(key, repo.Name()+"/")
+	if name == key {
+		return false
+	}
+	for _, suffix := range []string{".ref", ".prefix"} {
+		if strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			cfg := repo.GetConfig()
+			return repository.MatchRef(cfg.Branches, name) || repository.MatchRef(cfg.Tags, name)
+		}
+	}
+	return false
+}
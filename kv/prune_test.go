@@ -0,0 +1,127 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/apex/log"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPruneBranch verifies the keys which no longer exist in the branch are deleted.
+func TestPruneBranch(t *testing.T) {
+	repoPath := t.TempDir()
+	err := os.WriteFile(filepath.Join(repoPath, "kept.txt"), []byte("kept"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := &mocks.KV{T: t}
+	kv.Put(&api.KVPair{Key: "repository_mock/master/stale.txt", Value: []byte("stale")}, nil)                   //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repository_mock/master/kept.txt", Value: []byte("kept")}, nil)                     //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repository_mock/master.prefix", Value: []byte("old/repository_mock/master")}, nil) //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "old/repository_mock/master/kept.txt", Value: []byte("kept")}, nil)                 //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "other/master/file.txt", Value: []byte("other")}, nil)                              //nolint:errcheck
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{Prune: true}, T: t}
+
+	err = handler.UpdateToHead(repo)
+	assert.NoError(t, err)
+
+	pair, _, _ := kv.Get("repository_mock/master/kept.txt", nil)
+	assert.NotNil(t, pair)
+	pair, _, _ = kv.Get("repository_mock/master/stale.txt", nil)
+	assert.Nil(t, pair)
+	pair, _, _ = kv.Get("old/repository_mock/master/kept.txt", nil)
+	assert.Nil(t, pair)
+	pair, _, _ = kv.Get("other/master/file.txt", nil)
+	assert.NotNil(t, pair)
+	pair, _, _ = kv.Get("repository_mock/master.ref", nil)
+	assert.NotNil(t, pair)
+	pair, _, _ = kv.Get("repository_mock/master.prefix", nil)
+	if assert.NotNil(t, pair) {
+		assert.Equal(t, "repository_mock/master", string(pair.Value))
+	}
+
+	// The stale keys are pruned even when the ref is up to date, the ref
+	// itself is not written again
+	ref, _, _ := kv.Get("repository_mock/master.ref", nil)
+	kv.Put(&api.KVPair{Key: "repository_mock/master/stale.txt", Value: []byte("stale")}, nil) //nolint:errcheck
+	err = handler.UpdateToHead(repo)
+	assert.NoError(t, err)
+	pair, _, _ = kv.Get("repository_mock/master/stale.txt", nil)
+	assert.Nil(t, pair)
+	pair, _, _ = kv.Get("repository_mock/master/kept.txt", nil)
+	assert.NotNil(t, pair)
+	pair, _, _ = kv.Get("repository_mock/master.ref", nil)
+	assert.Equal(t, ref.ModifyIndex, pair.ModifyIndex)
+
+	// Nothing is written when the branch is in sync
+	handler.applied = 0
+	err = handler.UpdateToHead(repo)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, handler.AppliedOps())
+	assert.Empty(t, handler.KVTxnOps)
+}
+
+// TestPruneBranchWithoutPrefix ensures the entire KV is never pruned.
+func TestPruneBranchWithoutPrefix(t *testing.T) {
+	handler := &KVHandler{
+		API: &mocks.KV{T: t},
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	repo := &mocks.Repo{Path: t.TempDir(), Config: &config.Repo{Prune: true, SkipBranchName: true, SkipRepoName: true}, T: t}
+	err := handler.pruneBranch(repo)
+	assert.Error(t, err)
+}
+
+// TestIsMetadataKey verifies only the ref and prefix keys of the tracked
+// branches and tags are protected from the prune.
+func TestIsMetadataKey(t *testing.T) {
+	repo := &mocks.Repo{Config: &config.Repo{Branches: []string{"main", "release/*"}, Tags: []string{"v*"}}, T: t}
+	protected := []string{
+		"repository_mock/main.ref",
+		"repository_mock/main.prefix",
+		"repository_mock/release/1.2.ref",
+		"repository_mock/v1.0.0.ref",
+	}
+	for _, key := range protected {
+		assert.True(t, isMetadataKey(repo, key), key)
+	}
+	unprotected := []string{
+		"repository_mock/main/app.ref",
+		"repository_mock/main/settings.prefix",
+		"repository_mock/feature.ref",
+		"other/main.ref",
+		"repository_mock/main",
+	}
+	for _, key := range unprotected {
+		assert.False(t, isMetadataKey(repo, key), key)
+	}
+}
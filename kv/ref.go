@@ -25,10 +25,26 @@ import (
 	"github.com/hashicorp/consul/api"
 )
 
+// Returns the key of the local branch ref in the KV
+func refKey(repo repository.Repo, branchName string) string {
+	refFile := fmt.Sprintf("%s.ref", branchName)
+	return path.Join(repo.Name(), refFile)
+}
+
+// KVRef returns the local branch ref stored in the KV, or an empty string if
+// there is none. Unlike getKVRef it does not queue the check of the index, so
+// it can be called while an update is running.
+func (h *KVHandler) KVRef(repo repository.Repo, branchName string) (string, error) {
+	pair, _, err := h.Get(refKey(repo, branchName), nil)
+	if err != nil || pair == nil {
+		return "", err
+	}
+	return string(pair.Value), nil
+}
+
 // Get local branch ref from the KV
 func (h *KVHandler) getKVRef(repo repository.Repo, branchName string) (string, error) {
-	refFile := fmt.Sprintf("%s.ref", branchName)
-	key := path.Join(repo.Name(), refFile)
+	key := refKey(repo, branchName)
 
 	pair, _, err := h.Get(key, nil)
 	if err != nil {
@@ -50,22 +66,16 @@ func (h *KVHandler) getKVRef(repo repository.Repo, branchName string) (string, e
 	return string(pair.Value), nil
 }
 
-// Put the local branch ref to the KV
-func (h *KVHandler) putKVRef(repo repository.Repo, branchName string) error {
-	refFile := fmt.Sprintf("%s.ref", branchName)
-	key := path.Join(repo.Name(), refFile)
-
-	rawRef, err := repo.ResolveRevision(plumbing.Revision("refs/heads/" + branchName))
-	if err != nil {
-		return err
-	}
+// Put the commit of the local branch or tag ref to the KV
+func (h *KVHandler) putKVRef(repo repository.Repo, ref *plumbing.Reference) error {
+	key := refKey(repo, ref.Name().Short())
 
 	p := &api.KVPair{
 		Key:   key,
-		Value: []byte(rawRef.String()),
+		Value: []byte(ref.Hash().String()),
 	}
 
-	_, err = h.Put(p, nil)
+	_, err := h.Put(p, nil)
 	if err != nil {
 		return err
 	}
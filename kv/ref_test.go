@@ -25,6 +25,7 @@ import (
 	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/apex/log"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,14 +49,25 @@ func TestKVRef(t *testing.T) {
 	commit := branch.Hash().String()
 
 	t.Run("TestPutKVRef", func(t *testing.T) {
-		testPutKVRef(t, branch.Name().Short(), key, commit, handler, repo)
+		testPutKVRef(t, branch, key, commit, handler, repo)
 	})
 	t.Run("TestPutKVRefModifiedIndex", func(t *testing.T) {
-		testPutKVRefModifiedIndex(t, branch.Name().Short(), key, commit, handler, repo)
+		testPutKVRefModifiedIndex(t, branch, key, commit, handler, repo)
+	})
+	t.Run("TestReadKVRef", func(t *testing.T) {
+		kvRef, err := handler.KVRef(repo, branch.Name().Short())
+		assert.NoError(t, err)
+		assert.Equal(t, commit, kvRef)
+		// The read does not queue the check of the index
+		assert.Empty(t, handler.KVTxnOps)
+
+		kvRef, err = handler.KVRef(repo, "missing")
+		assert.NoError(t, err)
+		assert.Empty(t, kvRef)
 	})
 }
 
-func testPutKVRef(t *testing.T, branch string, key string, commit string, handler *KVHandler, repo repository.Repo) {
+func testPutKVRef(t *testing.T, branch *plumbing.Reference, key string, commit string, handler *KVHandler, repo repository.Repo) {
 	err := handler.putKVRef(repo, branch)
 	if err != nil {
 		t.Fatal(err)
@@ -64,8 +76,8 @@ func testPutKVRef(t *testing.T, branch string, key string, commit string, handle
 	assert.Equal(t, string(kvBranch.Value), commit)
 }
 
-func testPutKVRefModifiedIndex(t *testing.T, branch string, key string, commit string, handler *KVHandler, repo repository.Repo) {
-	lastCommit, err := handler.getKVRef(repo, branch)
+func testPutKVRefModifiedIndex(t *testing.T, branch *plumbing.Reference, key string, commit string, handler *KVHandler, repo repository.Repo) {
+	lastCommit, err := handler.getKVRef(repo, branch.Name().Short())
 	if err != nil {
 		t.Fatal(err)
 	}
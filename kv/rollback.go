@@ -0,0 +1,156 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// commitAtomic executes the transaction slices one by one. When a slice fails
+// the slices which were already committed are reverted with compensating
+// operations, so the KV is left in the state it had before the commit.
+func (h *KVHandler) commitAtomic(slices []api.KVTxnOps) error {
+	var ops api.KVTxnOps
+	for _, slice := range slices {
+		ops = append(ops, slice...)
+	}
+	state, err := h.captureState(ops)
+	if err != nil {
+		return fmt.Errorf("capturing the KV state before the commit failed: %w", err)
+	}
+	// The modify index of the keys written by the committed slices
+	written := make(map[string]uint64)
+	applied := h.applied
+	for i, slice := range slices {
+		results, err := h.executeTransaction(slice)
+		if err == nil {
+			for _, pair := range results {
+				if pair.ModifyIndex > written[pair.Key] {
+					written[pair.Key] = pair.ModifyIndex
+				}
+			}
+			continue
+		}
+		if i == 0 {
+			return err
+		}
+		h.logger.Warnf("Transaction slice %d of %d failed, rolling back the committed slices", i+1, len(slices))
+		rbErr := h.rollback(state, written, slices[:i])
+		if rbErr != nil {
+			return fmt.Errorf("rollback of the committed transaction slices failed: %v, after: %w", rbErr, err)
+		}
+		// Neither the reverted slices nor the rollback count as applied
+		h.applied = applied
+		return &TransactionIntegrityError{fmt.Sprintf("Transaction slice %d of %d failed and the committed slices have been rolled back due to: %s", i+1, len(slices), err)}
+	}
+	return nil
+}
+
+// Returns the current pairs of the keys modified by the operations. The keys
+// missing in the state did not exist before the commit. The keys under the
+// prefix of the updated branch are listed at once, the other keys are
+// queried one by one.
+func (h *KVHandler) captureState(ops api.KVTxnOps) (map[string]*api.KVPair, error) {
+	state := make(map[string]*api.KVPair)
+	if h.snapshot != nil {
+		pairs, _, err := h.List(h.snapshot.prefix, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			state[pair.Key] = pair
+		}
+	}
+	for _, op := range ops {
+		if h.snapshot.covers(op.Key) {
+			continue
+		}
+		// nolint: exhaustive
+		switch op.Verb {
+		case api.KVSet, api.KVDelete:
+			pair, _, err := h.Get(op.Key, nil)
+			if err != nil {
+				return nil, err
+			}
+			if pair != nil {
+				state[pair.Key] = pair
+			}
+		case api.KVDeleteTree:
+			pairs, _, err := h.List(op.Key, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, pair := range pairs {
+				state[pair.Key] = pair
+			}
+		}
+	}
+	return state, nil
+}
+
+// Restores the captured values of the keys modified by the committed slices.
+// The compensating operations are check-and-set ones, so a key modified by
+// another writer after the commit is not overwritten and fails the rollback.
+func (h *KVHandler) rollback(state map[string]*api.KVPair, written map[string]uint64, committed []api.KVTxnOps) error {
+	keys := make(map[string]bool)
+	for _, slice := range committed {
+		for _, op := range slice {
+			// nolint: exhaustive
+			switch op.Verb {
+			case api.KVSet, api.KVDelete:
+				keys[op.Key] = true
+			case api.KVDeleteTree:
+				for key := range state {
+					if strings.HasPrefix(key, op.Key) {
+						keys[key] = true
+					}
+				}
+			}
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var ops api.KVTxnOps
+	for _, key := range sorted {
+		// The index is zero for the keys deleted by the commit, so they are
+		// only restored if they still do not exist
+		index := written[key]
+		pair, existed := state[key]
+		switch {
+		case existed:
+			ops = append(ops, &api.KVTxnOp{Verb: api.KVCAS, Key: key, Value: pair.Value, Flags: pair.Flags, Index: index})
+		case index != 0:
+			ops = append(ops, &api.KVTxnOp{Verb: api.KVDeleteCAS, Key: key, Index: index})
+		}
+	}
+	for _, slice := range h.splitIntoSlices(ops, consulTxnSize) {
+		_, err := h.executeTransaction(slice)
+		if err != nil {
+			return err
+		}
+	}
+	h.logger.Infof("Rolled back %d keys", len(ops))
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/apex/log"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCommitAtomic verifies the committed slices are rolled back when the last slice fails.
+func TestCommitAtomic(t *testing.T) {
+	kv := &mocks.KV{T: t}
+	kv.Put(&api.KVPair{Key: "repo/main.ref", Value: []byte("abc")}, nil)  //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/key0", Value: []byte("old")}, nil) //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/tree/a", Value: []byte("a")}, nil) //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main/tree/b", Value: []byte("b")}, nil) //nolint:errcheck
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+		atomic: true,
+	}
+
+	ref, _, _ := kv.Get("repo/main.ref", nil)
+	// The ref has been modified by another instance in the meantime
+	handler.KVTxnOps = append(handler.KVTxnOps, &api.KVTxnOp{Verb: api.KVCheckIndex, Key: ref.Key, Index: ref.ModifyIndex + 1})
+	handler.DeleteTree("repo/main/tree", nil) //nolint:errcheck
+	for i := 0; i < 2*consulTxnSize; i++ {
+		handler.Put(&api.KVPair{Key: fmt.Sprintf("repo/main/key%d", i), Value: []byte("new")}, nil) //nolint:errcheck
+	}
+	handler.Put(&api.KVPair{Key: ref.Key, Value: []byte("def")}, nil) //nolint:errcheck
+
+	err := handler.Commit()
+	tiErr := &TransactionIntegrityError{}
+	assert.ErrorAs(t, err, &tiErr)
+	assert.Equal(t, 0, handler.AppliedOps())
+
+	pairs, _, _ := kv.List("repo/", nil)
+	values := map[string]string{}
+	for _, pair := range pairs {
+		values[pair.Key] = string(pair.Value)
+	}
+	assert.Equal(t, map[string]string{
+		"repo/main.ref":    "abc",
+		"repo/main/key0":   "old",
+		"repo/main/tree/a": "a",
+		"repo/main/tree/b": "b",
+	}, values)
+
+	// Without the atomic mode the committed slices are kept
+	handler.atomic = false
+	handler.KVTxnOps = append(handler.KVTxnOps, &api.KVTxnOp{Verb: api.KVCheckIndex, Key: ref.Key, Index: ref.ModifyIndex + 1})
+	for i := 0; i < 2*consulTxnSize; i++ {
+		handler.Put(&api.KVPair{Key: fmt.Sprintf("repo/main/key%d", i), Value: []byte("new")}, nil) //nolint:errcheck
+	}
+	handler.Put(&api.KVPair{Key: ref.Key, Value: []byte("def")}, nil) //nolint:errcheck
+	assert.ErrorAs(t, handler.Commit(), &tiErr)
+	pair, _, _ := kv.Get("repo/main/key0", nil)
+	assert.Equal(t, "new", string(pair.Value))
+}
+
+// concurrentKV runs the function after the first transaction, like another
+// writer modifying the KV in the meantime.
+type concurrentKV struct {
+	*mocks.KV
+	fn func()
+}
+
+func (kv *concurrentKV) Txn(txnops api.KVTxnOps, opts *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	status, response, meta, err := kv.KV.Txn(txnops, opts)
+	if kv.fn != nil {
+		kv.fn()
+		kv.fn = nil
+	}
+	return status, response, meta, err
+}
+
+// TestRollbackConcurrentWrite verifies the rollback does not overwrite the key
+// modified by another writer after the commit.
+func TestRollbackConcurrentWrite(t *testing.T) {
+	mock := &mocks.KV{T: t}
+	mock.Put(&api.KVPair{Key: "repo/main.ref", Value: []byte("abc")}, nil) //nolint:errcheck
+	kv := &concurrentKV{KV: mock}
+	kv.fn = func() {
+		mock.Put(&api.KVPair{Key: "repo/main/key1", Value: []byte("other")}, nil) //nolint:errcheck
+	}
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+		atomic: true,
+	}
+
+	ref, _, _ := mock.Get("repo/main.ref", nil)
+	handler.KVTxnOps = append(handler.KVTxnOps, &api.KVTxnOp{Verb: api.KVCheckIndex, Key: ref.Key, Index: ref.ModifyIndex + 1})
+	for i := 0; i < 2*consulTxnSize; i++ {
+		handler.Put(&api.KVPair{Key: fmt.Sprintf("repo/main/key%d", i), Value: []byte("new")}, nil) //nolint:errcheck
+	}
+	handler.Put(&api.KVPair{Key: ref.Key, Value: []byte("def")}, nil) //nolint:errcheck
+
+	err := handler.Commit()
+	assert.ErrorContains(t, err, "rollback of the committed transaction slices failed")
+	pair, _, _ := mock.Get("repo/main/key1", nil)
+	assert.Equal(t, "other", string(pair.Value))
+}
+
+// TestCaptureState verifies the keys under the prefix of the branch are
+// listed at once and the other keys are queried one by one.
+func TestCaptureState(t *testing.T) {
+	kv := &mocks.KV{T: t}
+	kv.Put(&api.KVPair{Key: "repo/main/a", Value: []byte("a")}, nil)     //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repo/main.ref", Value: []byte("abc")}, nil) //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "unrelated/b", Value: []byte("b")}, nil)     //nolint:errcheck
+	handler := &KVHandler{API: kv, snapshot: &snapshot{prefix: "repo/main/"}}
+
+	state, err := handler.captureState(api.KVTxnOps{
+		{Verb: api.KVSet, Key: "repo/main/a"},
+		{Verb: api.KVSet, Key: "repo/main/new"},
+		{Verb: api.KVSet, Key: "repo/main.ref"},
+	})
+	assert.NoError(t, err)
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	assert.ElementsMatch(t, []string{"repo/main/a", "repo/main.ref"}, keys)
+}
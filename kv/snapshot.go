@@ -0,0 +1,120 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// snapshot holds the values stored under the prefix of the branch, so the
+// unchanged values are not written again without querying every single key.
+type snapshot struct {
+	prefix string
+	values map[string][]byte
+}
+
+// Loads the values stored under the prefix of the checked out branch.
+func (h *KVHandler) loadSnapshot(repo repository.Repo) error {
+	h.snapshot = nil
+	prefix, _, err := pathBaseBuilder(repo)
+	if err != nil {
+		return err
+	}
+	// Never list the entire KV, the values are queried one by one instead
+	if prefix == "" {
+		return nil
+	}
+	prefix += "/"
+	pairs, _, err := h.List(prefix, nil)
+	if err != nil {
+		return err
+	}
+	values := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Key] = pair.Value
+	}
+	h.snapshot = &snapshot{prefix: prefix, values: values}
+	return nil
+}
+
+func (h *KVHandler) clearSnapshot() {
+	h.snapshot = nil
+}
+
+func (s *snapshot) covers(key string) bool {
+	return s != nil && strings.HasPrefix(key, s.prefix)
+}
+
+// Returns the current value of the key and whether the key exists.
+func (h *KVHandler) currentValue(key string) ([]byte, bool, error) {
+	if h.snapshot.covers(key) {
+		value, ok := h.snapshot.values[key]
+		return value, ok, nil
+	}
+	pair, _, err := h.Get(key, nil)
+	if err != nil || pair == nil {
+		return nil, false, err
+	}
+	return pair.Value, true, nil
+}
+
+// Returns the keys currently stored under the prefix.
+func (h *KVHandler) currentKeys(prefix string) ([]string, error) {
+	var keys []string
+	if h.snapshot.covers(prefix) {
+		for key := range h.snapshot.values {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+	pairs, _, err := h.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		keys = append(keys, pair.Key)
+	}
+	return keys, nil
+}
+
+// Keeps the snapshot in line with the operations added to the transaction.
+func (s *snapshot) set(key string, value []byte) {
+	if s.covers(key) {
+		s.values[key] = value
+	}
+}
+
+func (s *snapshot) delete(key string) {
+	if s.covers(key) {
+		delete(s.values, key)
+	}
+}
+
+func (s *snapshot) deleteTree(prefix string) {
+	if s == nil {
+		return
+	}
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.values, key)
+		}
+	}
+}
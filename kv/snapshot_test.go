@@ -0,0 +1,116 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
+	"github.com/apex/log"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPutKVUnchanged verifies the unchanged values are not written again.
+func TestPutKVUnchanged(t *testing.T) {
+	kv := &mocks.KV{T: t}
+	kv.Put(&api.KVPair{Key: "repository_mock/master/same.txt", Value: []byte("same")}, nil)   //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repository_mock/master/changed.txt", Value: []byte("old")}, nil) //nolint:errcheck
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	repo := &mocks.Repo{Path: t.TempDir(), Config: &config.Repo{}, T: t}
+
+	for _, snapshot := range []bool{false, true} {
+		handler.KVTxnOps = nil
+		handler.clearSnapshot()
+		if snapshot {
+			err := handler.loadSnapshot(repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		assert.NoError(t, handler.PutKV(repo, "same.txt", []byte("same")))
+		assert.NoError(t, handler.PutKV(repo, "changed.txt", []byte("new")))
+		assert.NoError(t, handler.PutKV(repo, "added.txt", []byte("added")))
+		if assert.Len(t, handler.KVTxnOps, 2) {
+			assert.Equal(t, "repository_mock/master/changed.txt", handler.KVTxnOps[0].Key)
+			assert.Equal(t, "repository_mock/master/added.txt", handler.KVTxnOps[1].Key)
+		}
+	}
+
+	// The snapshot follows the queued operations
+	handler.KVTxnOps = nil
+	assert.NoError(t, handler.DeleteKV(repo, "same.txt"))
+	assert.NoError(t, handler.PutKV(repo, "same.txt", []byte("same")))
+	assert.Len(t, handler.KVTxnOps, 2)
+}
+
+// TestUpdateExpandedFile verifies only the changed and removed entries are written.
+func TestUpdateExpandedFile(t *testing.T) {
+	repoPath := t.TempDir()
+	filePath := filepath.Join(repoPath, "app.yml")
+	err := os.WriteFile(filePath, []byte("same: 1\nchanged: new\nadded: true\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := &mocks.KV{T: t}
+	kv.Put(&api.KVPair{Key: "repository_mock/master/app/same", Value: []byte("1")}, nil)       //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repository_mock/master/app/changed", Value: []byte("old")}, nil)  //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repository_mock/master/app/removed", Value: []byte("gone")}, nil) //nolint:errcheck
+	kv.Put(&api.KVPair{Key: "repository_mock/master/apps/other", Value: []byte("other")}, nil) //nolint:errcheck
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{ExpandKeys: true}, T: t}
+	err = handler.loadSnapshot(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Init(filePath, repo).Update(handler, repo)
+	assert.NoError(t, err)
+
+	ops := map[string]api.KVOp{}
+	for _, op := range handler.KVTxnOps {
+		ops[op.Key] = op.Verb
+	}
+	assert.Equal(t, map[string]api.KVOp{
+		"repository_mock/master/app/changed": api.KVSet,
+		"repository_mock/master/app/added":   api.KVSet,
+		"repository_mock/master/app/removed": api.KVDelete,
+	}, ops)
+
+	// An invalid file keeps the stored entries
+	handler.KVTxnOps = nil
+	err = os.WriteFile(filePath, []byte("same: [\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Init(filePath, repo).Update(handler, repo)
+	assert.Error(t, err)
+	assert.Empty(t, handler.KVTxnOps)
+}
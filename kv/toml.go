@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// Create function creates the KV store entries based on the file content.
+func (f *TOMLFile) Create(kv Handler, repo repository.Repo) error {
+	return createEntries(kv, repo, f)
+}
+
+// Update functions updates the KV store based on the file content.
+func (f *TOMLFile) Update(kv Handler, repo repository.Repo) error {
+	return updateEntries(kv, repo, f)
+}
+
+// Delete removes the key-value pairs from the KV store under given prefix.
+func (f *TOMLFile) Delete(kv Handler, repo repository.Repo) error {
+	return deleteEntries(kv, repo, f)
+}
+
+// GetPath returns the path to the file.
+func (f *TOMLFile) GetPath() string {
+	return f.path
+}
+
+// Evaluates the TOML file. Tables and arrays of tables become the segments
+// of the key path.
+func (f *TOMLFile) decode(content []byte) (interface{}, error) {
+	tomlTree := make(map[string]interface{})
+	err := toml.Unmarshal(content, &tomlTree)
+	if err != nil {
+		return nil, err
+	}
+	return tomlTree, nil
+}
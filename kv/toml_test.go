@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTOMLFileEntries verifies evaluation of the TOML files.
+func TestTOMLFileEntries(t *testing.T) {
+	content := `title = "orders"
+port = 8080
+ratio = 0.5
+debug = false
+created = 1979-05-27T07:32:00Z
+
+[database]
+hosts = ["db1", "db2"]
+
+[database.pool]
+size = 10
+
+[[backends]]
+name = "first"
+
+[[backends]]
+name = "second"
+`
+	entries, err := evaluate(&TOMLFile{}, []byte(content))
+	assert.NoError(t, err)
+	expected := map[string]string{
+		"title":              "orders",
+		"port":               "8080",
+		"ratio":              "0.5",
+		"debug":              "false",
+		"created":            "1979-05-27T07:32:00Z",
+		"database/hosts/0":   "db1",
+		"database/hosts/1":   "db2",
+		"database/pool/size": "10",
+		"backends/0/name":    "first",
+		"backends/1/name":    "second",
+	}
+	assert.Len(t, entries, len(expected))
+	for key, value := range expected {
+		assert.Equal(t, value, string(entries[key]), key)
+	}
+}
+
+// TestTOMLFileInvalid ensures syntax errors are reported.
+func TestTOMLFileInvalid(t *testing.T) {
+	_, err := evaluate(&TOMLFile{}, []byte("[database\nport = 1"))
+	assert.Error(t, err)
+}
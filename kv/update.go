@@ -21,40 +21,61 @@ import (
 
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/apex/log"
-	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 )
 
 // HandleUpdate handles the update of a particular repository.
 func (h *KVHandler) HandleUpdate(repo repository.Repo) error {
-	w, err := repo.Worktree()
-	config := repo.GetConfig()
+	return h.handleUpdate(repo, false)
+}
+
+// ForceUpdate handles the update of the repository like HandleUpdate, but
+// pushes the branches and the tags even if their ref is up to date, so the
+// values which drifted from the repository are repaired.
+func (h *KVHandler) ForceUpdate(repo repository.Repo) error {
+	return h.handleUpdate(repo, true)
+}
+
+func (h *KVHandler) handleUpdate(repo repository.Repo, force bool) error {
+	h.applied = 0
 	repo.Lock()
 	defer repo.Unlock()
 
+	refs, err := repo.TrackedRefs()
 	if err != nil {
 		return err
 	}
 
-	for _, branch := range config.Branches {
-		ref := fmt.Sprintf("refs/heads/%s", branch)
-		err := w.Checkout(&git.CheckoutOptions{
-			Branch: plumbing.ReferenceName(ref),
-			Force:  true,
-		})
+	for _, ref := range refs {
+		err := repository.Checkout(repo, ref)
 		if err != nil {
 			return fmt.Errorf("checkout %s failed: %w", ref, err)
 		}
-		err = h.UpdateToHead(repo)
+		err = h.updateToHead(repo, force)
 		if err != nil {
 			return fmt.Errorf("updateToHead %s failed: %w", repo.Name(), err)
 		}
+		h.Metrics.Synced(repo.Name(), ref.Short())
 	}
 	return nil
 }
 
 // UpdateToHead handles update to current HEAD comparing diffs against the KV.
 func (h *KVHandler) UpdateToHead(repo repository.Repo) error {
+	return h.updateToHead(repo, false)
+}
+
+// Updates the KV to the current HEAD. The forced update pushes the entire
+// branch even if the ref is up to date.
+func (h *KVHandler) updateToHead(repo repository.Repo, force bool) (err error) {
+	// Discard the queued operations when the update fails, so neither the
+	// files nor the ref are written and the next update retries the branch
+	defer func() {
+		if err != nil {
+			h.KVTxnOps = nil
+		}
+	}()
+	config := repo.GetConfig()
 	head, err := repo.Head()
 	if err != nil {
 		return fmt.Errorf("get repo head failed, err=%w", err)
@@ -70,23 +91,24 @@ func (h *KVHandler) UpdateToHead(repo repository.Repo) error {
 		return fmt.Errorf("getKVRef failed, refName=%v err=%w", refName, err)
 	}
 
+	err = h.loadSnapshot(repo)
+	if err != nil {
+		return fmt.Errorf("loading KV snapshot failed, refName=%v err=%w", refName, err)
+	}
+	defer h.clearSnapshot()
+
 	// Local ref
 	headRefHash := head.Hash().String()
 	// log.Debugf("(consul) kvRef: %s | localRef: %s", kvRef, localRef)
 
-	if kvRef == "" {
+	switch {
+	case kvRef == "":
 		log.Infof("init KV PUT branch: %s/%s", repo.Name(), refName)
 		err := h.putBranch(repo, plumbing.ReferenceName(head.Name().Short()))
 		if err != nil {
 			return err
 		}
-
-		err = h.putKVRef(repo, refName)
-		if err != nil {
-			return err
-		}
-		h.logger.Infof("init KV PUT ref: %s/%s", repo.Name(), refName)
-	} else if kvRef != headRefHash {
+	case kvRef != headRefHash:
 		// Check if the ref belongs to that repo
 		err := repo.CheckRef(refName)
 		if err != nil {
@@ -100,18 +122,45 @@ func (h *KVHandler) UpdateToHead(repo repository.Repo) error {
 		}
 		err = h.handleDeltas(repo, deltas)
 		if err != nil {
-			h.logger.Errorf("handleDeltas error: %v, repo=%v", err, repo)
-			// TODO should we return err here?
+			return err
 		}
-
-		err = h.putKVRef(repo, refName)
+	case config.Prune || force:
+		// The KV might have drifted from the branch even if the ref is up to date,
+		// i.e. when the source_root has changed, so push the entire branch again.
+		// The unchanged values are not written again.
+		h.logger.Infof("KV ref is up to date, reconciling the branch: %s/%s", repo.Name(), refName)
+		err := h.putBranch(repo, plumbing.ReferenceName(head.Name().Short()))
 		if err != nil {
 			return err
 		}
-		h.logger.Infof("KV PUT ref change: %s/%s", repo.Name(), refName)
-	} else {
+	default:
 		h.logger.Infof("KV ref is update to date: %s/%s", repo.Name(), refName)
+		return nil
+	}
+
+	if config.Prune {
+		err = h.pruneBranch(repo)
+		if err != nil {
+			return fmt.Errorf("prune %s/%s failed: %w", repo.Name(), refName, err)
+		}
+	}
+
+	// The ref is only written when it moved, so reconciling an up to date
+	// branch does not bump its modify index on every poll
+	if kvRef == headRefHash {
+		if !h.hasChanges() {
+			h.KVTxnOps = nil
+			h.logger.Infof("KV is up to date: %s/%s", repo.Name(), refName)
+			return nil
+		}
+		return h.Commit()
+	}
+
+	err = h.putKVRef(repo, head)
+	if err != nil {
+		return err
 	}
+	h.logger.Infof("KV PUT ref: %s/%s", repo.Name(), refName)
 
 	return nil
 }
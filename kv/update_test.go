@@ -28,6 +28,7 @@ import (
 	"github.com/KohlsTechnology/git2consul-go/config"
 	"github.com/KohlsTechnology/git2consul-go/kv/mocks"
 	"github.com/apex/log"
+	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -51,8 +52,8 @@ func TestUpdateToHead(t *testing.T) {
 	initialCommit := branch.Hash().String()
 	repo.Pull(branch.Name().Short()) //nolint:errcheck
 	// Make an initial load to the Consul KV store.
-	handler.putBranch(repo, branch.Name())        //nolint:errcheck
-	handler.putKVRef(repo, branch.Name().Short()) //nolint:errcheck
+	handler.putBranch(repo, branch.Name()) //nolint:errcheck
+	handler.putKVRef(repo, branch)         //nolint:errcheck
 	// Fake commit
 	f, err := ioutil.TempFile(repoPath, "example.txt")
 	assert.NoError(t, err)
@@ -63,8 +64,11 @@ func TestUpdateToHead(t *testing.T) {
 	// Pull the change.
 	repo.Pull(branch.Name().Short()) //nolint:errcheck
 
+	handler.applied = 0
 	err = handler.UpdateToHead(repo)
 	assert.NoError(t, err)
+	// The put of the file and of the ref
+	assert.Equal(t, 2, handler.AppliedOps())
 	branch, err = repo.Head()
 	assert.NoError(t, err)
 
@@ -87,3 +91,70 @@ func TestUpdateToHead(t *testing.T) {
 	// 	return nil
 	// })
 }
+
+// TestUpdateToHeadLargeFileFail verifies the sync fails and the ref is not
+// written when a file exceeds the max_value_size with the fail policy.
+func TestUpdateToHeadLargeFileFail(t *testing.T) {
+	handler := &KVHandler{
+		API: &mocks.KV{T: t},
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	repoPath := t.TempDir()
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{MaxValueSize: 10, LargeFiles: config.LargeFilesFail}, T: t}
+	repo.Pull("master") //nolint:errcheck
+	err := os.WriteFile(filepath.Join(repoPath, "large.txt"), []byte("more than ten bytes"), 0o600)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(repoPath, "small.txt"), []byte("small"), 0o600)
+	assert.NoError(t, err)
+
+	err = handler.UpdateToHead(repo)
+	assert.Error(t, err)
+	assert.Empty(t, handler.KVTxnOps)
+
+	branch, err := repo.Head()
+	assert.NoError(t, err)
+	pair, _, err := handler.Get(refKey(repo, branch.Name().Short()), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, pair)
+	pair, _, err = handler.Get(filepath.Join(repo.Name(), branch.Name().Short(), "small.txt"), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, pair)
+}
+
+// TestForceUpdate verifies the forced update repairs the values which drifted
+// from the branch while its ref is up to date.
+func TestForceUpdate(t *testing.T) {
+	kv := &mocks.KV{T: t}
+	handler := &KVHandler{
+		API: kv,
+		logger: log.WithFields(log.Fields{
+			"caller": "consul",
+		}),
+	}
+	repoPath := t.TempDir()
+	repo := &mocks.Repo{Path: repoPath, Config: &config.Repo{}, T: t}
+	repo.Pull("master") //nolint:errcheck
+	err := os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("value"), 0o600)
+	assert.NoError(t, err)
+	assert.NoError(t, handler.UpdateToHead(repo))
+
+	key := "repository_mock/master/app.txt"
+	kv.Put(&api.KVPair{Key: key, Value: []byte("drifted")}, nil) //nolint:errcheck
+	ref, _, _ := kv.Get("repository_mock/master.ref", nil)
+
+	// The regular update only compares the refs
+	handler.applied = 0
+	assert.NoError(t, handler.updateToHead(repo, false))
+	assert.Equal(t, 0, handler.AppliedOps())
+	pair, _, _ := kv.Get(key, nil)
+	assert.Equal(t, "drifted", string(pair.Value))
+
+	assert.NoError(t, handler.updateToHead(repo, true))
+	assert.Equal(t, 1, handler.AppliedOps())
+	pair, _, _ = kv.Get(key, nil)
+	assert.Equal(t, "value", string(pair.Value))
+	pair, _, _ = kv.Get("repository_mock/master.ref", nil)
+	assert.Equal(t, ref.ModifyIndex, pair.ModifyIndex)
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -37,6 +38,7 @@ const (
 	ExitCodeError = 10 + iota
 	ExitCodeFlagError
 	ExitCodeConfigError
+	ExitCodePlanChanges
 
 	ExitCodeOk int = 0
 )
@@ -47,6 +49,8 @@ func main() {
 		printVersion     bool
 		once             bool
 		dumpSampleConfig bool
+		plan             bool
+		planfmt          string
 		loglvl           string
 		logfmt           string
 	)
@@ -55,9 +59,19 @@ func main() {
 	flag.BoolVar(&printVersion, "version", false, "show version")
 	flag.BoolVar(&once, "once", false, "run git2consul once and exit")
 	flag.BoolVar(&dumpSampleConfig, "dump", false, "dump sample config")
+	flag.BoolVar(&plan, "plan", false, "print the KV changes without writing them, exit with non-zero code if there are pending changes")
+	flag.StringVar(&planfmt, "planfmt", "text", "specify plan output format [ text | json ]")
 	// allow switching logformat. Structured output helps with parsers
 	flag.StringVar(&logfmt, "logfmt", "", "specify log format [ text | cli | json ] ")
 	flag.StringVar(&loglvl, "loglvl", "", "set log level [debug | info | warn | error ]")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n  %s [options]\n  %s validate file...\n\nOptions:\n", os.Args[0], os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
 	flag.Parse()
 
 	if printVersion {
@@ -79,6 +93,11 @@ func main() {
 		flag.Usage()
 		os.Exit(ExitCodeFlagError)
 	}
+	if plan && planfmt != "text" && planfmt != "json" {
+		log.Errorf("Unknown plan format: %s", planfmt)
+		flag.Usage()
+		os.Exit(ExitCodeFlagError)
+	}
 
 	// init before load config
 	initLogger("debug", "text")
@@ -102,6 +121,10 @@ func main() {
 
 	log.WithField("config", cfg.String()).Info("loaded config")
 
+	if plan {
+		os.Exit(runPlan(cfg, planfmt))
+	}
+
 	theRunner, err := runner.NewRunner(cfg, once)
 	if err != nil {
 		log.Errorf("(runner): %s", err)
@@ -111,11 +134,12 @@ func main() {
 
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh,
-		syscall.SIGHUP,
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT,
 	)
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
 
 	for {
 		select {
@@ -128,10 +152,81 @@ func main() {
 		case <-signalCh:
 			log.Info("Received interrupt. Cleaning up...")
 			theRunner.Stop()
+		case <-reloadCh:
+			log.Info("Received hangup. Reloading the configuration...")
+			reload(theRunner, filename)
 		}
 	}
 }
 
+// Reloads the repositories of the configuration file. On error the running
+// configuration is kept.
+func reload(theRunner *runner.Runner, filename string) {
+	cfg, err := config.Load(filename)
+	if err != nil {
+		log.Errorf("(config): %s", err)
+		return
+	}
+	err = theRunner.Reload(cfg)
+	if err != nil {
+		log.Errorf("(reload): %s", err)
+	}
+}
+
+// Prints the problems of the configuration files and returns the exit code.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s validate:\n  %s validate file...\n\nReports all the problems of the configuration files.\n", os.Args[0], os.Args[0])
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeFlagError
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "No configuration file provided")
+		flags.Usage()
+		return ExitCodeFlagError
+	}
+
+	exitCode := ExitCodeOk
+	for _, file := range flags.Args() {
+		errs, err := config.Validate(file)
+		if err != nil {
+			errs = []*config.ValidationError{{File: file, Err: err}}
+		}
+		for _, err := range errs {
+			fmt.Println(err)
+			exitCode = ExitCodeConfigError
+		}
+	}
+	return exitCode
+}
+
+// Prints the KV changes pending on the sync and returns the exit code.
+func runPlan(cfg *config.Config, format string) int {
+	thePlan, err := runner.Plan(cfg)
+	if err != nil {
+		log.Errorf("(plan): %s", err)
+		return ExitCodeError
+	}
+
+	switch format {
+	case "json":
+		err = thePlan.WriteJSON(os.Stdout)
+	default:
+		err = thePlan.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Errorf("(plan): %s", err)
+		return ExitCodeError
+	}
+
+	if len(thePlan.Changes()) > 0 {
+		return ExitCodePlanChanges
+	}
+	return ExitCodeOk
+}
+
 func initLogger(level string, format string) {
 	logLevel := log.MustParseLevel(level)
 	log.SetLevel(logLevel)
@@ -0,0 +1,190 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the Prometheus metrics of git2consul. The methods
+// of a nil *Metrics do nothing, so the metrics are optional everywhere.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "git2consul"
+
+// Outcomes of the pulls
+const (
+	PullChanged  = "changed"
+	PullUpToDate = "up_to_date"
+	PullFailed   = "failed"
+)
+
+// Metrics is the registry of the metrics of git2consul.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	pulls           *prometheus.CounterVec
+	fetchDuration   *prometheus.HistogramVec
+	transactions    *prometheus.CounterVec
+	transactionOps  prometheus.Histogram
+	transactionSize prometheus.Histogram
+	retries         *prometheus.CounterVec
+	webhookRequests *prometheus.CounterVec
+
+	// sinceLastSync describes the gauge computed when the metrics are scraped
+	sinceLastSync *prometheus.Desc
+	mu            sync.Mutex
+	lastSync      map[syncKey]time.Time
+}
+
+type syncKey struct {
+	repo   string
+	branch string
+}
+
+// New creates the metrics and registers them along with the Go and process
+// collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		pulls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pulls_total",
+			Help:      "Pulls of the branches by outcome.",
+		}, []string{"repository", "branch", "outcome"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "Duration of the pulls of the branches.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{"repository", "branch"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kv_transactions_total",
+			Help:      "KV transactions sent to Consul by outcome.",
+		}, []string{"outcome"}),
+		transactionOps: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "kv_transaction_operations",
+			Help:      "Operations of the KV transactions.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 7),
+		}),
+		transactionSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "kv_transaction_bytes",
+			Help:      "Size of the values of the KV transactions.",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 6),
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kv_transaction_retries_total",
+			Help:      "Updates of the repositories retried after a transaction integrity error.",
+		}, []string{"repository"}),
+		webhookRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_requests_total",
+			Help:      "Webhook requests by provider and status code.",
+		}, []string{"provider", "code"}),
+		sinceLastSync: prometheus.NewDesc(
+			namespace+"_seconds_since_last_sync",
+			"Seconds since the last successful sync of the branch to the KV.",
+			[]string{"repository", "branch"}, nil,
+		),
+		lastSync: make(map[syncKey]time.Time),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.pulls, m.fetchDuration, m.transactions, m.transactionOps, m.transactionSize, m.retries, m.webhookRequests,
+		m,
+	)
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObservePull records the outcome and the duration of the pull of a branch.
+func (m *Metrics) ObservePull(repo, branch, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.pulls.WithLabelValues(repo, branch, outcome).Inc()
+	m.fetchDuration.WithLabelValues(repo, branch).Observe(duration.Seconds())
+}
+
+// ObserveTransaction records a KV transaction, its number of operations and
+// the size of its values.
+func (m *Metrics) ObserveTransaction(ops, size int, succeeded bool) {
+	if m == nil {
+		return
+	}
+	outcome := "succeeded"
+	if !succeeded {
+		outcome = "failed"
+	}
+	m.transactions.WithLabelValues(outcome).Inc()
+	m.transactionOps.Observe(float64(ops))
+	m.transactionSize.Observe(float64(size))
+}
+
+// IncRetries records the retry of the update of the repository.
+func (m *Metrics) IncRetries(repo string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(repo).Inc()
+}
+
+// ObserveWebhook records a webhook request and its status code.
+func (m *Metrics) ObserveWebhook(provider string, code int) {
+	if m == nil {
+		return
+	}
+	m.webhookRequests.WithLabelValues(provider, strconv.Itoa(code)).Inc()
+}
+
+// Synced records the successful sync of the branch to the KV.
+func (m *Metrics) Synced(repo, branch string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastSync[syncKey{repo, branch}] = time.Now()
+}
+
+// Describe implements prometheus.Collector for the gauge of the last sync.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.sinceLastSync
+}
+
+// Collect implements prometheus.Collector for the gauge of the last sync.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, last := range m.lastSync {
+		ch <- prometheus.MustNewConstMetric(m.sinceLastSync, prometheus.GaugeValue, time.Since(last).Seconds(), key.repo, key.branch)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.ObservePull("example", "main", PullChanged, time.Second)
+	m.ObservePull("example", "main", PullUpToDate, time.Second)
+	m.ObservePull("example", "main", PullUpToDate, time.Second)
+	m.ObserveTransaction(64, 2048, true)
+	m.ObserveTransaction(3, 10, false)
+	m.IncRetries("example")
+	m.ObserveWebhook("github", http.StatusAccepted)
+	m.Synced("example", "main")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.pulls.WithLabelValues("example", "main", PullChanged)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.pulls.WithLabelValues("example", "main", PullUpToDate)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transactions.WithLabelValues("succeeded")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transactions.WithLabelValues("failed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.retries.WithLabelValues("example")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.webhookRequests.WithLabelValues("github", "202")))
+	assert.Less(t, testutil.ToFloat64(m), 1.0)
+
+	rw := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	body := rw.Body.String()
+	assert.Contains(t, body, `git2consul_fetch_duration_seconds_count{branch="main",repository="example"} 3`)
+	assert.Contains(t, body, `git2consul_kv_transaction_operations_sum 67`)
+	assert.Contains(t, body, `git2consul_kv_transaction_bytes_sum 2058`)
+	assert.Contains(t, body, `git2consul_seconds_since_last_sync{branch="main",repository="example"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	assert.NotPanics(t, func() {
+		m.ObservePull("example", "main", PullFailed, time.Second)
+		m.ObserveTransaction(1, 1, true)
+		m.IncRetries("example")
+		m.ObserveWebhook("github", http.StatusOK)
+		m.Synced("example", "main")
+	})
+}
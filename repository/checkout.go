@@ -18,8 +18,7 @@ package repository
 
 import (
 	"errors"
-	"fmt"
-	"path"
+	"strings"
 
 	"github.com/apex/log"
 
@@ -49,10 +48,11 @@ func (r *Repository) checkoutConfigBranches() error {
 	}
 
 	_ = refIter.ForEach(func(b *plumbing.Reference) error {
-		branchOnRemote := StringInSlice(path.Base(b.Name().String()), r.Config.Branches)
-		if branchOnRemote {
+		// The remote branches are named like origin/release/1.2
+		branchName := strings.TrimPrefix(b.Name().Short(), "origin/")
+		if branchName != "HEAD" && MatchRef(r.GetConfig().Branches, branchName) {
 			err := w.Checkout(&git.CheckoutOptions{
-				Branch: plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", b.Name())),
+				Branch: plumbing.NewBranchReferenceName(branchName),
 				Force:  true,
 			})
 			if err != nil {
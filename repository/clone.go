@@ -28,12 +28,13 @@ func (r *Repository) Clone(path string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if len(r.Config.Branches) == 0 {
-		return fmt.Errorf("No tracked branches specified")
+	cfg := r.GetConfig()
+	if len(cfg.Branches) == 0 && len(cfg.Tags) == 0 {
+		return fmt.Errorf("No tracked branches or tags specified")
 	}
 
 	rawRepo, err := git.PlainClone(path, false, &git.CloneOptions{
-		URL:  r.Config.URL,
+		URL:  cfg.URL,
 		Auth: r.Authentication,
 	})
 	if err != nil {
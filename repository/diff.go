@@ -17,6 +17,7 @@ limitations under the License.
 package repository
 
 import (
+	"path"
 	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing"
@@ -56,7 +57,25 @@ func (r *Repository) DiffStatus(ref string) (object.Changes, error) {
 	if err != nil {
 		return nil, err
 	}
-	return applySourceRoot(diff, sourceRoot), nil
+	// The files are selected by the ignore file of their own tree, so the
+	// files whose selection changed with the ignore file are added or deleted
+	fromFilter, err := newTreeFilter(r.GetConfig(), t0)
+	if err != nil {
+		return nil, err
+	}
+	toFilter, err := newTreeFilter(r.GetConfig(), t1)
+	if err != nil {
+		return nil, err
+	}
+	changes := applyFilter(applySourceRoot(diff, sourceRoot), fromFilter, toFilter)
+	if !ignoreFileChanged(diff) {
+		return changes, nil
+	}
+	selected, err := selectionChanges(diff, t0, t1, sourceRoot, fromFilter, toFilter)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, selected...), nil
 }
 
 func applySourceRoot(changes object.Changes, sourceRoot string) object.Changes {
@@ -75,3 +94,62 @@ func applySourceRoot(changes object.Changes, sourceRoot string) object.Changes {
 	}
 	return selected
 }
+
+// Selects the changes of the files matched by the filters of the compared
+// trees. A change is turned into a deletion when its file is no longer
+// selected, and into an insertion when its file was not selected before.
+func applyFilter(changes object.Changes, fromFilter, toFilter *Filter) object.Changes {
+	var selected object.Changes
+	empty := object.ChangeEntry{}
+	for _, change := range changes {
+		from := change.From != empty && fromFilter.Match(change.From.Name)
+		to := change.To != empty && toFilter.Match(change.To.Name)
+		switch {
+		case from && to:
+			selected = append(selected, change)
+		case from:
+			selected = append(selected, &object.Change{From: change.From})
+		case to:
+			selected = append(selected, &object.Change{To: change.To})
+		}
+	}
+	return selected
+}
+
+// Returns true if the ignore file was added, modified or deleted.
+func ignoreFileChanged(changes object.Changes) bool {
+	for _, change := range changes {
+		if change.From.Name == IgnoreFile || change.To.Name == IgnoreFile {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the insertions and deletions of the unchanged files whose selection
+// differs between the filters of the compared trees.
+func selectionChanges(changes object.Changes, t0, t1 *object.Tree, sourceRoot string, fromFilter, toFilter *Filter) (object.Changes, error) {
+	changed := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		changed[change.From.Name] = true
+		changed[change.To.Name] = true
+	}
+	var selected object.Changes
+	err := t1.Files().ForEach(func(f *object.File) error {
+		if changed[f.Name] || !strings.HasPrefix(f.Name, sourceRoot) {
+			return nil
+		}
+		from := fromFilter.Match(f.Name)
+		if from == toFilter.Match(f.Name) {
+			return nil
+		}
+		entry := object.TreeEntry{Name: path.Base(f.Name), Mode: f.Mode, Hash: f.Hash}
+		if from {
+			selected = append(selected, &object.Change{From: object.ChangeEntry{Name: f.Name, Tree: t0, TreeEntry: entry}})
+		} else {
+			selected = append(selected, &object.Change{To: object.ChangeEntry{Name: f.Name, Tree: t1, TreeEntry: entry}})
+		}
+		return nil
+	})
+	return selected, err
+}
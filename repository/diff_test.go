@@ -67,3 +67,97 @@ func TestDiffStatus(t *testing.T) {
 
 	assert.Equal(t, action, merkletrie.Insert)
 }
+
+func TestDiffStatusFilter(t *testing.T) {
+	remoteRepo, remotePath := mocks.InitRemote(t)
+	defer os.RemoveAll(remotePath)
+
+	repoConfig := mock.RepoConfig(remotePath)
+	repoConfig.Exclude = []string{"*.tmpl"}
+	dstPath, err := ioutil.TempDir("", repoConfig.Name)
+	defer os.RemoveAll(dstPath)
+	assert.Nil(t, err)
+
+	localRepo, err := git.PlainClone(dstPath, false, &git.CloneOptions{URL: repoConfig.URL})
+	assert.Nil(t, err)
+
+	repo := &Repository{
+		Repository: localRepo,
+		Config:     repoConfig,
+	}
+
+	h, err := repo.Head()
+	assert.Nil(t, err)
+
+	oldRef := h.Hash().String()
+
+	mocks.Add(t, remoteRepo, "tree/test.yml", []byte("foo"))
+	mocks.Add(t, remoteRepo, "tree/test.yml.tmpl", []byte("{{ foo }}"))
+	mocks.Add(t, remoteRepo, IgnoreFile, []byte("README*\n"))
+	mocks.Add(t, remoteRepo, "README.md", []byte("readme"))
+	mocks.Commit(t, remoteRepo, "Add files.")
+
+	err = repo.Pull("master")
+	assert.Nil(t, err)
+
+	deltas, err := repo.DiffStatus(oldRef)
+	assert.Nil(t, err)
+
+	if assert.Len(t, deltas, 1) {
+		assert.Equal(t, "tree/test.yml", deltas[0].To.Name)
+	}
+}
+
+func TestDiffStatusIgnoreFileChange(t *testing.T) {
+	remoteRepo, remotePath := mocks.InitRemote(t)
+	defer os.RemoveAll(remotePath)
+
+	mocks.Add(t, remoteRepo, IgnoreFile, []byte("*.tmpl\n"))
+	mocks.Add(t, remoteRepo, "tree/app.yml", []byte("foo"))
+	mocks.Add(t, remoteRepo, "tree/app.yml.tmpl", []byte("{{ foo }}"))
+	mocks.Add(t, remoteRepo, "tree/notes.txt", []byte("notes"))
+	mocks.Commit(t, remoteRepo, "Add files.")
+
+	repoConfig := mock.RepoConfig(remotePath)
+	dstPath, err := ioutil.TempDir("", repoConfig.Name)
+	defer os.RemoveAll(dstPath)
+	assert.Nil(t, err)
+
+	localRepo, err := git.PlainClone(dstPath, false, &git.CloneOptions{URL: repoConfig.URL})
+	assert.Nil(t, err)
+
+	repo := &Repository{
+		Repository: localRepo,
+		Config:     repoConfig,
+	}
+
+	h, err := repo.Head()
+	assert.Nil(t, err)
+
+	oldRef := h.Hash().String()
+
+	// The template is no longer ignored while the notes are
+	mocks.Add(t, remoteRepo, IgnoreFile, []byte("tree/*.txt\n"))
+	mocks.Commit(t, remoteRepo, "Change the ignore file.")
+
+	err = repo.Pull("master")
+	assert.Nil(t, err)
+
+	deltas, err := repo.DiffStatus(oldRef)
+	assert.Nil(t, err)
+
+	actions := make(map[string]merkletrie.Action)
+	for _, delta := range deltas {
+		action, err := delta.Action()
+		assert.Nil(t, err)
+		if action == merkletrie.Delete {
+			actions[delta.From.Name] = action
+		} else {
+			actions[delta.To.Name] = action
+		}
+	}
+	assert.Equal(t, map[string]merkletrie.Action{
+		"tree/app.yml.tmpl": merkletrie.Insert,
+		"tree/notes.txt":    merkletrie.Delete,
+	}, actions)
+}
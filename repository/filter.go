@@ -0,0 +1,187 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// IgnoreFile is the name of the file in the repository root listing the
+// patterns of the files which are not pushed to the KV.
+const IgnoreFile = ".git2consulignore"
+
+// Filter selects the files of the repository which are pushed to the KV,
+// based on the include and exclude patterns of the repository and its
+// ignore file.
+type Filter struct {
+	include []string
+	exclude []string
+	ignore  []ignorePattern
+}
+
+type ignorePattern struct {
+	pattern string
+	negate  bool
+}
+
+// NewFilter creates the filter of the repository from its configuration
+// and the ignore file of the checked out branch.
+func NewFilter(r Repo) (*Filter, error) {
+	content, err := os.ReadFile(filepath.Join(WorkDir(r), IgnoreFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return newFilter(r.GetConfig(), content)
+}
+
+// Creates the filter of the repository from the ignore file of the tree.
+func newTreeFilter(cfg *config.Repo, tree *object.Tree) (*Filter, error) {
+	file, err := tree.File(IgnoreFile)
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return newFilter(cfg, nil)
+		}
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return newFilter(cfg, []byte(content))
+}
+
+// Creates the filter from the configuration and the content of the ignore
+// file, which is nil if there is no ignore file.
+func newFilter(cfg *config.Repo, content []byte) (*Filter, error) {
+	ignore, err := parseIgnore(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", IgnoreFile, err)
+	}
+	return &Filter{
+		include: cfg.Include,
+		exclude: cfg.Exclude,
+		ignore:  ignore,
+	}, nil
+}
+
+func parseIgnore(content []byte) ([]ignorePattern, error) {
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := ignorePattern{pattern: line}
+		if strings.HasPrefix(line, "!") {
+			p = ignorePattern{pattern: line[1:], negate: true}
+		}
+		err := config.ValidatePattern(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, scanner.Err()
+}
+
+// Match returns true if the file should be pushed to the KV. The name is
+// the slash separated path of the file relative to the repository root.
+func (f *Filter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	name = strings.TrimPrefix(name, "/")
+	if name == IgnoreFile {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	if matchAny(f.exclude, name) {
+		return false
+	}
+	// The last matching pattern of the ignore file wins
+	ignored := false
+	for _, p := range f.ignore {
+		if matchPattern(p.pattern, name) {
+			ignored = !p.negate
+		}
+	}
+	return !ignored
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches the slash separated path against the glob pattern.
+// A pattern without a slash matches the name of the file or of any of its
+// parent directories, like "*.tmpl" or "docs". Other patterns are matched
+// from the repository root, where "**" matches any number of directories,
+// like "ci/**/*.yml". A pattern matching a directory matches all the files
+// under the directory.
+func matchPattern(pattern, name string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	segments := strings.Split(name, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), segments)
+}
+
+// Matches the pattern segments against the leading path segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
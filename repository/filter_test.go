@@ -0,0 +1,67 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.tmpl", "app/config.yml.tmpl", true},
+		{"*.tmpl", "app/config.yml", false},
+		{"README*", "README.md", true},
+		{"docs", "docs/index.md", true},
+		{"docs/", "app/docs/index.md", true},
+		{"app/*.yml", "app/config.yml", true},
+		{"app/*.yml", "app/nested/config.yml", false},
+		{"app/**/*.yml", "app/config.yml", true},
+		{"app/**/*.yml", "app/nested/deep/config.yml", true},
+		{"/ci", "ci/pipeline.yml", true},
+		{"/ci", "app/ci/pipeline.yml", false},
+		{"**/secrets", "app/secrets/key", true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.match, matchPattern(test.pattern, test.name), "%s %s", test.pattern, test.name)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	ignore, err := parseIgnore([]byte("# comment\n\n*.yml\n!config.yml\n"))
+	assert.NoError(t, err)
+	f := &Filter{
+		include: []string{"app"},
+		exclude: []string{"*.tmpl"},
+		ignore:  ignore,
+	}
+	assert.True(t, f.Match("app/config.yml"))
+	assert.True(t, f.Match("app/data.json"))
+	assert.False(t, f.Match("app/local.yml"))
+	assert.False(t, f.Match("app/data.json.tmpl"))
+	assert.False(t, f.Match("README.md"))
+	assert.False(t, (&Filter{}).Match(IgnoreFile))
+	assert.True(t, (*Filter)(nil).Match("README.md"))
+
+	_, err = parseIgnore([]byte("valid\n[invalid\n"))
+	assert.EqualError(t, err, `line 2: "[invalid": syntax error in pattern`)
+}
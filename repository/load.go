@@ -26,31 +26,14 @@ import (
 // LoadRepos populates Repository slice from configuration. It also
 // handles cloning of the repository if not present
 func LoadRepos(cfg *config.Config) ([]*Repository, error) {
-	logger := log.WithFields(log.Fields{
-		"caller": "repository",
-	})
 	repos := []*Repository{}
 
 	// Create Repository object for each repo
 	for _, repoConfig := range cfg.Repos {
-
-		auth, err := GetAuth(repoConfig)
+		r, err := LoadRepo(cfg.LocalStore, repoConfig)
 		if err != nil {
-			return nil, fmt.Errorf("Error getting AuthMethod: %w", err)
+			return nil, err
 		}
-
-		r, state, err := New(cfg.LocalStore, repoConfig, auth)
-		if err != nil {
-			return nil, fmt.Errorf("Error loading %s: %w", repoConfig.Name, err)
-		}
-
-		switch state {
-		case RepositoryCloned:
-			logger.Infof("Cloned repository %s", r.Name())
-		case RepositoryOpened:
-			logger.Infof("Loaded repository %s", r.Name())
-		}
-
 		repos = append(repos, r)
 	}
 
@@ -60,3 +43,29 @@ func LoadRepos(cfg *config.Config) ([]*Repository, error) {
 
 	return repos, nil
 }
+
+// LoadRepo opens the local copy of the repository in the local store, cloning
+// it if there is none.
+func LoadRepo(localStore string, repoConfig *config.Repo) (*Repository, error) {
+	logger := log.WithFields(log.Fields{
+		"caller": "repository",
+	})
+
+	auth, err := GetAuth(repoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting AuthMethod: %w", err)
+	}
+
+	r, state, err := New(localStore, repoConfig, auth)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading %s: %w", repoConfig.Name, err)
+	}
+
+	switch state {
+	case RepositoryCloned:
+		logger.Infof("Cloned repository %s", r.Name())
+	case RepositoryOpened:
+		logger.Infof("Loaded repository %s", r.Name())
+	}
+	return r, nil
+}
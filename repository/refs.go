@@ -0,0 +1,207 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// MatchRef checks if the branch or tag name matches any of the names or glob
+// patterns, like "release/*". The "*" does not match the "/" separator.
+func MatchRef(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the local ref is a branch or a tag tracked by the configuration.
+func (r *Repository) tracks(name plumbing.ReferenceName) bool {
+	switch {
+	case name.IsBranch():
+		// HEAD of the remote is fetched as a branch
+		return name.Short() != "HEAD" && MatchRef(r.GetConfig().Branches, name.Short())
+	case name.IsTag():
+		return MatchRef(r.GetConfig().Tags, name.Short())
+	}
+	return false
+}
+
+// TrackedRefs returns the local branches and tags matching the branches and
+// the tags of the configuration, the branches first, sorted by name.
+func (r *Repository) TrackedRefs() ([]plumbing.ReferenceName, error) {
+	refs, err := r.Storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	var tracked []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if r.tracks(ref.Name()) {
+			tracked = append(tracked, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tracked, func(i, j int) bool {
+		if tracked[i].IsBranch() != tracked[j].IsBranch() {
+			return tracked[i].IsBranch()
+		}
+		return tracked[i] < tracked[j]
+	})
+	return tracked, nil
+}
+
+// FetchRefs fetches the tracked branches which are new on the remote, and the
+// tracked tags which are new or moved, so that the branch patterns pick up
+// the new branches. The branches already fetched are updated by Pull. The
+// tracked branches and tags deleted on the remote are removed from the local
+// copy. It returns the fetched refs.
+func (r *Repository) FetchRefs() ([]plumbing.ReferenceName, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return nil, err
+	}
+	remoteRefs, err := remote.List(&git.ListOptions{Auth: r.Authentication})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.pruneRefs(remoteRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	var fetched []plumbing.ReferenceName
+	var refSpecs []config.RefSpec
+	for _, remoteRef := range remoteRefs {
+		name := remoteRef.Name()
+		// Skip the peeled tags and the untracked refs
+		if strings.HasSuffix(name.String(), "^{}") || !r.tracks(name) {
+			continue
+		}
+		local, err := r.Reference(name, false)
+		if err == nil && (name.IsBranch() || local.Hash() == remoteRef.Hash()) {
+			continue
+		}
+		fetched = append(fetched, name)
+		refSpecs = append(refSpecs, config.RefSpec("+"+name.String()+":"+name.String()))
+	}
+	if len(refSpecs) == 0 {
+		return nil, nil
+	}
+
+	err = r.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   refSpecs,
+		Auth:       r.Authentication,
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+	return fetched, nil
+}
+
+// Removes the local tracked branches and tags which are missing from the
+// refs of the remote.
+func (r *Repository) pruneRefs(remoteRefs []*plumbing.Reference) error {
+	remote := make(map[plumbing.ReferenceName]bool, len(remoteRefs))
+	for _, ref := range remoteRefs {
+		remote[ref.Name()] = true
+	}
+	refs, err := r.Storer.IterReferences()
+	if err != nil {
+		return err
+	}
+	var vanished []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if r.tracks(ref.Name()) && !remote[ref.Name()] {
+			vanished = append(vanished, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range vanished {
+		log.Infof("Removing %s of %s, it was deleted on the remote", name.Short(), r.Name())
+		err = r.Storer.RemoveReference(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Head returns the reference of the checked out branch or tag. The annotated
+// tags are resolved to their commit.
+func (r *Repository) Head() (*plumbing.Reference, error) {
+	head, err := r.Repository.Head()
+	if err != nil || !head.Name().IsTag() {
+		return head, err
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(head.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return plumbing.NewHashReference(head.Name(), *hash), nil
+}
+
+// Checkout checks out the branch or the tag. The HEAD of a tag refers to the
+// tag, so that its keys are stored under the tag name like the ones of the
+// branches.
+func Checkout(r Repo, ref plumbing.ReferenceName) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if !ref.IsTag() {
+		return w.Checkout(&git.CheckoutOptions{
+			Branch: ref,
+			Force:  true,
+		})
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return err
+	}
+	err = w.Checkout(&git.CheckoutOptions{
+		Hash:  *hash,
+		Force: true,
+	})
+	if err != nil {
+		return err
+	}
+	return r.GetStorer().SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref))
+}
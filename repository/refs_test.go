@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/config/mock"
+	"github.com/KohlsTechnology/git2consul-go/repository/mocks"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRef(t *testing.T) {
+	patterns := []string{"main", "release/*", "v1.?"}
+	assert.True(t, MatchRef(patterns, "main"))
+	assert.True(t, MatchRef(patterns, "release/1.2"))
+	assert.True(t, MatchRef(patterns, "v1.4"))
+	assert.False(t, MatchRef(patterns, "release/1.2/hotfix"))
+	assert.False(t, MatchRef(patterns, "feature/main"))
+	assert.False(t, MatchRef(patterns, "v1.40"))
+	assert.False(t, MatchRef(nil, "main"))
+}
+
+func TestTrackedRefs(t *testing.T) {
+	remote, remotePath := mocks.InitRemote(t)
+	defer os.RemoveAll(remotePath)
+	head, err := remote.Head()
+	require.NoError(t, err)
+	branch := func(name string) {
+		require.NoError(t, remote.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), head.Hash())))
+	}
+	branch("release/1.2")
+	branch("feature")
+	_, err = remote.CreateTag("v1.0.0", head.Hash(), nil)
+	require.NoError(t, err)
+	_, err = remote.CreateTag("v1.1.0", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "foo", Email: "foo@foo.foo", When: time.Now()},
+		Message: "Release 1.1.0",
+	})
+	require.NoError(t, err)
+
+	repoConfig := mock.RepoConfig(remotePath)
+	repoConfig.Branches = []string{head.Name().Short(), "release/*"}
+	repoConfig.Tags = []string{"v1.*"}
+	repo, _, err := New(t.TempDir(), repoConfig, nil)
+	require.NoError(t, err)
+
+	refs, err := repo.TrackedRefs()
+	assert.NoError(t, err)
+	assert.Equal(t, []plumbing.ReferenceName{head.Name(), "refs/heads/release/1.2", "refs/tags/v1.0.0", "refs/tags/v1.1.0"}, refs)
+
+	// The new branches and tags matching the patterns are fetched once
+	branch("release/1.3")
+	branch("hotfix")
+	_, err = remote.CreateTag("v1.2.0", head.Hash(), nil)
+	require.NoError(t, err)
+	fetched, err := repo.FetchRefs()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []plumbing.ReferenceName{"refs/heads/release/1.3", "refs/tags/v1.2.0"}, fetched)
+	fetched, err = repo.FetchRefs()
+	assert.NoError(t, err)
+	assert.Empty(t, fetched)
+	refs, err = repo.TrackedRefs()
+	assert.NoError(t, err)
+	assert.Contains(t, refs, plumbing.ReferenceName("refs/heads/release/1.3"))
+
+	// The branches and tags deleted on the remote are removed
+	require.NoError(t, remote.Storer.RemoveReference("refs/heads/release/1.3"))
+	require.NoError(t, remote.DeleteTag("v1.2.0"))
+	fetched, err = repo.FetchRefs()
+	assert.NoError(t, err)
+	assert.Empty(t, fetched)
+	refs, err = repo.TrackedRefs()
+	assert.NoError(t, err)
+	assert.NotContains(t, refs, plumbing.ReferenceName("refs/heads/release/1.3"))
+	assert.NotContains(t, refs, plumbing.ReferenceName("refs/tags/v1.2.0"))
+	assert.Contains(t, refs, plumbing.ReferenceName("refs/heads/release/1.2"))
+
+	// The checked out tag is the head, resolved to its commit
+	require.NoError(t, Checkout(repo, "refs/tags/v1.1.0"))
+	tag, err := repo.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, plumbing.ReferenceName("refs/tags/v1.1.0"), tag.Name())
+	assert.Equal(t, head.Hash(), tag.Hash())
+
+	require.NoError(t, Checkout(repo, "refs/heads/release/1.2"))
+	current, err := repo.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, plumbing.ReferenceName("refs/heads/release/1.2"), current.Name())
+	// The branch is pulled after the tag, the new remote branches are fetched by the first pull
+	assert.NoError(t, repo.Pull("release/1.2"))
+	assert.ErrorIs(t, repo.Pull("release/1.2"), git.NoErrAlreadyUpToDate)
+}
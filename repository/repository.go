@@ -46,6 +46,8 @@ type Repo interface {
 	GetConfig() *config.Repo
 	GetStorer() storage.Storer
 	ResolveRevision(plumbing.Revision) (*plumbing.Hash, error)
+	TrackedRefs() ([]plumbing.ReferenceName, error)
+	FetchRefs() ([]plumbing.ReferenceName, error)
 }
 
 // Repository is used to hold the git repository object and it's configuration
@@ -55,6 +57,8 @@ type Repository struct {
 	// see https://github.com/uber-go/guide/blob/master/style.md#zero-value-mutexes-are-valid
 	// see also gocritic exposedSyncMutex https://go-critic.com/overview.html#exposedsyncmutex
 	mu sync.Mutex
+	// cfgMu guards the Config, which is replaced on reconfigure
+	cfgMu sync.RWMutex
 
 	*git.Repository
 	Config         *config.Repo
@@ -70,9 +74,23 @@ const (
 
 // GetConfig returns config *Repo
 func (r *Repository) GetConfig() *config.Repo {
+	r.cfgMu.RLock()
+	defer r.cfgMu.RUnlock()
 	return r.Config
 }
 
+// Reconfigure replaces the configuration and the authentication of the
+// repository once its running pull, fetch or KV update is over. The local copy
+// is kept, so the url of the configuration must not change.
+func (r *Repository) Reconfigure(repoConfig *config.Repo, auth transport.AuthMethod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cfgMu.Lock()
+	defer r.cfgMu.Unlock()
+	r.Config = repoConfig
+	r.Authentication = auth
+}
+
 // GetStorer returns Storer
 func (r *Repository) GetStorer() storage.Storer {
 	return r.Storer
@@ -80,7 +98,7 @@ func (r *Repository) GetStorer() storage.Storer {
 
 // Name returns the repository name
 func (r *Repository) Name() string {
-	return r.Config.Name
+	return r.GetConfig().Name
 }
 
 // Branch returns the branch name
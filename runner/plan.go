@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/kv"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/go-git/go-git/v5"
+)
+
+// Plan pulls the tracked branches and tags of every repository and runs the regular
+// update of the KV in the dry-run mode. It returns the plan with the changes
+// the sync would apply, without writing anything to the KV.
+func Plan(cfg *config.Config) (*kv.Plan, error) {
+	repos, err := repository.LoadRepos(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load repositories from configuration: %w", err)
+	}
+
+	handler, err := kv.New(cfg.Consul)
+	if err != nil {
+		return nil, err
+	}
+	plan := handler.DryRun()
+
+	for _, repo := range repos {
+		_, err := repo.FetchRefs()
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s failed: %w", repo.Name(), err)
+		}
+		refs, err := repo.TrackedRefs()
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if !ref.IsBranch() {
+				continue
+			}
+			err := repo.Pull(ref.Short())
+			if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+				return nil, fmt.Errorf("pull %s/%s failed: %w", repo.Name(), ref.Short(), err)
+			}
+		}
+		err = handler.HandleUpdate(repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// Reload applies the repositories of the configuration to the running
+// watcher. The new repositories are cloned, and the repositories whose
+// configuration changed are reconfigured in place, so their running work
+// finishes before the new configuration applies. On error the repositories
+// are left untouched.
+//
+// The local_store, webhook and consul configurations are bound to the
+// running listener and client, so their changes require a restart.
+func (r *Runner) Reload(cfg *config.Config) error {
+	if r.cfg.LocalStore != cfg.LocalStore || !reflect.DeepEqual(r.cfg.Webhook, cfg.Webhook) || !reflect.DeepEqual(r.cfg.Consul, cfg.Consul) {
+		r.logger.Warn("The changes of local_store, webhook and consul require a restart, ignoring them")
+	}
+
+	repos := make([]repository.Repo, 0, len(cfg.Repos))
+	var reconfigure []func()
+	for _, repoConfig := range cfg.Repos {
+		current, ok := r.watcher.Repository(repoConfig.Name)
+		if ok && reflect.DeepEqual(current.GetConfig(), repoConfig) {
+			repos = append(repos, current)
+			continue
+		}
+		if ok {
+			// The local copy keeps the remote it was cloned from
+			if current.GetConfig().URL != repoConfig.URL {
+				return fmt.Errorf("Changing the url of %s is not supported by reload", repoConfig.Name)
+			}
+			repo, isRepository := current.(*repository.Repository)
+			if !isRepository {
+				return fmt.Errorf("Repository %s cannot be reconfigured", repoConfig.Name)
+			}
+			auth, err := repository.GetAuth(repoConfig)
+			if err != nil {
+				return fmt.Errorf("Error getting AuthMethod: %w", err)
+			}
+			repoConfig := repoConfig
+			reconfigure = append(reconfigure, func() { repo.Reconfigure(repoConfig, auth) })
+			repos = append(repos, current)
+			continue
+		}
+		repo, err := repository.LoadRepo(r.cfg.LocalStore, repoConfig)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, repo)
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("No repositories provided in the configuration")
+	}
+
+	for _, fn := range reconfigure {
+		fn()
+	}
+	r.watcher.Reload(repos)
+	r.cfg.Repos = cfg.Repos
+	r.logger.Infof("Reloaded the configuration of %d repositories", len(repos))
+	return nil
+}
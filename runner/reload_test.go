@@ -0,0 +1,90 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/config/mock"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/KohlsTechnology/git2consul-go/repository/mocks"
+	"github.com/KohlsTechnology/git2consul-go/watch"
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/discard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	log.SetHandler(discard.New())
+	_, remotePath := mocks.InitRemote(t)
+	defer os.RemoveAll(remotePath)
+
+	cfg := mock.Config(remotePath)
+	defer os.RemoveAll(cfg.LocalStore)
+	name := cfg.Repos[0].Name
+	repos, err := repository.LoadRepos(cfg)
+	require.NoError(t, err)
+	w := watch.New([]repository.Repo{repos[0]}, cfg.Webhook, false)
+	defer w.Stop()
+	r := &Runner{logger: log.WithField("caller", "runner"), cfg: cfg, watcher: w}
+
+	// Every reload reads a new configuration
+	newConfig := func(repoConfigs ...*config.Repo) *config.Config {
+		reloaded := mock.Config(remotePath)
+		os.RemoveAll(reloaded.LocalStore)
+		reloaded.LocalStore = cfg.LocalStore
+		reloaded.Repos = repoConfigs
+		return reloaded
+	}
+
+	// The unchanged repository keeps running
+	assert.NoError(t, r.Reload(newConfig(mock.RepoConfig(remotePath))))
+	current, ok := w.Repository(name)
+	assert.True(t, ok)
+	assert.Same(t, repos[0], current)
+
+	// The changed repository is reconfigured in place and the new one is cloned
+	changed := mock.RepoConfig(remotePath)
+	changed.MountPoint = "config"
+	added := mock.RepoConfig(remotePath)
+	added.Name = "git2consul-test-added"
+	assert.NoError(t, r.Reload(newConfig(changed, added)))
+	current, ok = w.Repository(name)
+	assert.True(t, ok)
+	assert.Same(t, repos[0], current)
+	assert.Equal(t, "config", current.GetConfig().MountPoint)
+	_, ok = w.Repository(added.Name)
+	assert.True(t, ok)
+	assert.DirExists(t, filepath.Join(cfg.LocalStore, added.Name))
+
+	// The removed repository is no longer watched
+	assert.NoError(t, r.Reload(newConfig(mock.RepoConfig(remotePath))))
+	_, ok = w.Repository(added.Name)
+	assert.False(t, ok)
+	assert.Len(t, w.Repositories, 1)
+
+	// The change of the url is rejected, the running repositories are kept
+	moved := mock.RepoConfig(remotePath + "-moved")
+	assert.Error(t, r.Reload(newConfig(moved, added)))
+	_, ok = w.Repository(added.Name)
+	assert.False(t, ok)
+	assert.Error(t, r.Reload(newConfig()))
+}
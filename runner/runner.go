@@ -23,6 +23,7 @@ import (
 
 	"github.com/KohlsTechnology/git2consul-go/config"
 	"github.com/KohlsTechnology/git2consul-go/kv"
+	"github.com/KohlsTechnology/git2consul-go/metrics"
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/KohlsTechnology/git2consul-go/watch"
 	"github.com/apex/log"
@@ -41,9 +42,14 @@ type Runner struct {
 
 	once bool
 
+	// cfg is the configuration the runner was started or reloaded with
+	cfg *config.Config
+
 	kvHandler kv.Handler
 
 	watcher *watch.Watcher
+
+	metrics *metrics.Metrics
 }
 
 // NewRunner creates a new runner instance
@@ -68,6 +74,12 @@ func NewRunner(cfg *config.Config, once bool) (*Runner, error) {
 	if err != nil {
 		return nil, err
 	}
+	watcher.KVRefs = handler
+
+	// Share the metrics between the handler and the watcher
+	m := metrics.New()
+	handler.Metrics = m
+	watcher.Metrics = m
 
 	runner := &Runner{
 		logger:    logger,
@@ -75,8 +87,10 @@ func NewRunner(cfg *config.Config, once bool) (*Runner, error) {
 		RcvDoneCh: make(chan struct{}, 1),
 		SndDoneCh: make(chan struct{}, 1),
 		once:      once,
+		cfg:       cfg,
 		kvHandler: handler,
 		watcher:   watcher,
+		metrics:   m,
 	}
 
 	return runner, nil
@@ -90,19 +104,34 @@ func (r *Runner) Start() {
 
 	for {
 		select {
-		case repo := <-r.watcher.RepoChangeCh:
+		case change := <-r.watcher.RepoChangeCh:
+			// The change was queued before its repository was removed by a reload
+			if !r.watcher.Watched(change.Repo) {
+				r.logger.Infof("Dropped the change of the removed repository %s", change.Repo.Name())
+				change.Done(0, watch.ErrRepoRemoved)
+				continue
+			}
 			// Handle change, and return if error on handler
 			retry := 0
 			var err error
 			for ok := true; ok && retry < 3; retry++ {
-				err = r.kvHandler.HandleUpdate(repo)
+				if change.Force {
+					err = r.kvHandler.ForceUpdate(change.Repo)
+				} else {
+					err = r.kvHandler.HandleUpdate(change.Repo)
+				}
 				tiErr := &kv.TransactionIntegrityError{}
 				// func As(err error, target interface{}) bool, `*target` must be `interface` or implement `error`
 				// in this case is, `*kv.TransactionIntegrityError` implement `error`,
 				// so our target param should be `**kv.TransactionIntegrityError`
 				ok = errors.As(err, &tiErr)
+				// The last attempt is not retried
+				if ok && retry < 2 {
+					r.metrics.IncRetries(change.Repo.Name())
+				}
 				time.Sleep(1000 * time.Millisecond)
 			}
+			change.Done(r.kvHandler.AppliedOps(), err)
 			if err != nil {
 				r.ErrCh <- err
 			}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sync"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// Change notifies that a repository changed and the KV should be updated.
+// The consumer of the change reports the outcome of the update with Done.
+type Change struct {
+	Repo repository.Repo
+	// Force makes the update push the branches even if they are up to date
+	Force bool
+
+	mu      sync.Mutex
+	waiters []func(int, error)
+}
+
+// NewChange creates the change of the repository.
+func NewChange(repo repository.Repo) *Change {
+	return &Change{Repo: repo}
+}
+
+// Done reports the outcome of the KV update, the number of the applied
+// operations and the error, to the waiters of the change.
+func (c *Change) Done(ops int, err error) {
+	c.mu.Lock()
+	waiters := c.waiters
+	c.waiters = nil
+	c.mu.Unlock()
+	for _, fn := range waiters {
+		fn(ops, err)
+	}
+}
+
+// Moves the waiters of the other change to the change.
+func (c *Change) merge(other *Change) {
+	other.mu.Lock()
+	waiters := other.waiters
+	other.waiters = nil
+	other.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiters = append(c.waiters, waiters...)
+	c.Force = c.Force || other.Force
+}
+
+// Registers a function called with the outcome of the KV update.
+func (c *Change) onDone(fn func(int, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiters = append(c.waiters, fn)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+var errWatcherStopped = errors.New("watcher stopped")
+
+// debouncer keeps the changes waiting for the end of the debounce window
+// of their repository.
+type debouncer struct {
+	mu      sync.Mutex
+	pending map[string]*Change
+}
+
+func newDebouncer() *debouncer {
+	return &debouncer{pending: make(map[string]*Change)}
+}
+
+// Returns the debounce window of the repository, the longest of its hooks.
+func debounceWindow(repo repository.Repo) time.Duration {
+	window := time.Duration(0)
+	for _, hook := range repo.GetConfig().Hooks {
+		if hook.Debounce > window {
+			window = hook.Debounce
+		}
+	}
+	return window
+}
+
+// Notifies the change of the repository. The changes notified within the
+// debounce window of the repository, starting from the first one, collapse
+// into one change sent at the end of the window.
+func (w *Watcher) notify(change *Change) {
+	window := debounceWindow(change.Repo)
+	if window == 0 || w.once {
+		w.send(change)
+		return
+	}
+
+	name := change.Repo.Name()
+	w.debouncer.mu.Lock()
+	defer w.debouncer.mu.Unlock()
+	if pending, ok := w.debouncer.pending[name]; ok {
+		w.logger.WithField("repository", name).Debug("Change coalesced with the pending one")
+		pending.merge(change)
+		return
+	}
+	w.debouncer.pending[name] = change
+	time.AfterFunc(window, func() {
+		w.debouncer.mu.Lock()
+		delete(w.debouncer.pending, name)
+		w.debouncer.mu.Unlock()
+		w.send(change)
+	})
+}
+
+// Sends the change to RepoChangeCh, failing it if the watcher stops. The
+// outcome of the KV update is recorded in the status of the repository.
+func (w *Watcher) send(change *Change) {
+	name := change.Repo.Name()
+	if !w.Watched(change.Repo) {
+		w.logger.WithField("repository", name).Info("Dropped the change of the removed repository")
+		change.Done(0, ErrRepoRemoved)
+		return
+	}
+	change.onDone(func(_ int, err error) { w.status.synced(name, err) })
+	select {
+	case w.RepoChangeCh <- change:
+	case <-w.RcvDoneCh:
+		change.Done(0, errWatcherStopped)
+	}
+}
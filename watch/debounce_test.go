@@ -0,0 +1,77 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebounce(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	repo.config.Hooks = []*config.Hook{
+		{Type: "polling", Interval: time.Minute},
+		{Type: "webhook", Debounce: 50 * time.Millisecond},
+	}
+	w := New([]repository.Repo{repo}, &config.WebhookServerConfig{}, false)
+	w.RepoChangeCh = make(chan *Change, 4)
+	defer w.Stop()
+
+	var outcomes []error
+	for i := 0; i < 3; i++ {
+		change := NewChange(repo)
+		change.onDone(func(_ int, err error) { outcomes = append(outcomes, err) })
+		w.notify(change)
+	}
+	assert.Len(t, w.RepoChangeCh, 0)
+
+	// The changes notified within the window collapse into one
+	change := <-w.RepoChangeCh
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, w.RepoChangeCh, 0)
+
+	// Every waiter gets the outcome of the KV update
+	change.Done(0, errors.New("consul is down"))
+	assert.Len(t, outcomes, 3)
+	for _, err := range outcomes {
+		assert.EqualError(t, err, "consul is down")
+	}
+
+	// The next change starts a new window
+	w.notify(NewChange(repo))
+	select {
+	case <-w.RepoChangeCh:
+	case <-time.After(time.Second):
+		t.Fatal("The change was not sent")
+	}
+}
+
+func TestDebounceDisabled(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	w := New([]repository.Repo{repo}, &config.WebhookServerConfig{}, false)
+	w.RepoChangeCh = make(chan *Change, 4)
+	defer w.Stop()
+
+	w.notify(NewChange(repo))
+	w.notify(NewChange(repo))
+	assert.Len(t, w.RepoChangeCh, 2)
+}
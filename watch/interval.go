@@ -18,7 +18,6 @@ package watch
 
 import (
 	"errors"
-	"path"
 	"sync"
 	"time"
 
@@ -26,12 +25,11 @@ import (
 
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
 )
 
-// Watch the repo by interval. This is called as a go routine since
-// ticker blocks
-func (w *Watcher) pollByInterval(repo repository.Repo, wg *sync.WaitGroup) {
+// Watch the repo by interval until the stop channel or the watcher is
+// closed. This is called as a go routine since ticker blocks
+func (w *Watcher) pollByInterval(repo repository.Repo, stop <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	config := repo.GetConfig()
 
@@ -68,49 +66,49 @@ func (w *Watcher) pollByInterval(repo repository.Repo, wg *sync.WaitGroup) {
 		if w.once {
 			return
 		}
+		w.status.polled(repo.Name(), time.Now().Add(interval))
 
 		select {
 		case <-ticker.C:
+		case <-stop:
+			return
 		case <-w.RcvDoneCh:
 			return
 		}
 	}
 }
 
+// Fetches the refs and pulls the tracked branches of the repository. The
+// branches are pulled even if the fetch failed, its error is returned once
+// the pull is over.
 func (w *Watcher) pollBranches(repo repository.Repo) error {
-	storer := repo.GetStorer()
-	config := repo.GetConfig()
-	itr, err := repository.LocalBranches(storer)
+	// The new branches and tags are fetched first, so that they are synced too
+	_, changed, fetchErr := w.fetchRefs(repo)
+	refs, err := repo.TrackedRefs()
 	if err != nil {
 		return err
 	}
-	changed := false
-
-	checkoutBranchFn := func(b *plumbing.Reference) error {
-		branchOnRemote := repository.StringInSlice(path.Base(b.Name().String()), config.Branches)
-		if branchOnRemote {
-			branchName := b.Name().Short()
-			err := repo.Pull(branchName)
-			if errors.Is(err, git.NoErrAlreadyUpToDate) {
-				w.logger.Debugf("Up to date: %s/%s", repo.Name(), branchName)
-			} else if err != nil {
-				w.logger.Debugf("Unable to pull \"%s\" branch because of \"%s\"", branchName, err)
-			} else {
-				w.logger.Infof("Changed: %s/%s", repo.Name(), branchName)
-				changed = true
-			}
-		}
-		return nil
-	}
 
-	err = itr.ForEach(checkoutBranchFn)
-	if err != nil {
-		return err
+	for _, ref := range refs {
+		// The tags are only fetched
+		if !ref.IsBranch() {
+			continue
+		}
+		branchName := ref.Short()
+		err := w.pull(repo, branchName)
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			w.logger.Debugf("Up to date: %s/%s", repo.Name(), branchName)
+		} else if err != nil {
+			w.logger.Debugf("Unable to pull \"%s\" branch because of \"%s\"", branchName, err)
+		} else {
+			w.logger.Infof("Changed: %s/%s", repo.Name(), branchName)
+			changed = true
+		}
 	}
 
 	if changed {
-		w.RepoChangeCh <- repo
+		w.notify(NewChange(repo))
 	}
 
-	return nil
+	return fetchErr
 }
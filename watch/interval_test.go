@@ -17,6 +17,7 @@ limitations under the License.
 package watch
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -49,7 +50,8 @@ func TestPollBranches(t *testing.T) {
 
 	w := &Watcher{
 		Repositories: []repository.Repo{repo},
-		RepoChangeCh: make(chan repository.Repo, 1),
+		registry:     newRegistry([]repository.Repo{repo}),
+		RepoChangeCh: make(chan *Change, 1),
 		ErrCh:        make(chan error),
 		RcvDoneCh:    make(chan struct{}, 1),
 		SndDoneCh:    make(chan struct{}, 1),
@@ -63,3 +65,15 @@ func TestPollBranches(t *testing.T) {
 
 	assert.FileExists(t, filepath.Join(repository.WorkDir(repo), "example", "check_interval.txt"))
 }
+
+// TestPollBranchesFetchError verifies the branches are pulled even if the
+// fetch fails, and the error of the fetch is returned.
+func TestPollBranchesFetchError(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	repo.fetchErr = errors.New("remote unreachable")
+	w := newWebhookWatcher(t, repo)
+
+	err := w.pollBranches(repo)
+	assert.ErrorContains(t, err, "remote unreachable")
+	assert.Equal(t, []string{"main"}, repo.pulls)
+}
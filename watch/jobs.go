@@ -0,0 +1,264 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/go-git/go-git/v5"
+	"github.com/gorilla/mux"
+)
+
+// Statuses of the jobs
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	// jobQueueSize is the number of jobs waiting for a worker
+	jobQueueSize = 100
+	// jobRetention is the number of jobs kept for the status requests
+	jobRetention = 1000
+)
+
+// Job is the sync of the branches of a repository requested by a webhook.
+type Job struct {
+	ID         string     `json:"id"`
+	Repo       string     `json:"repository"`
+	Branches   []string   `json:"branches"`
+	Status     string     `json:"status"`
+	Messages   []string   `json:"messages,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Operations int        `json:"operations"`
+	Created    time.Time  `json:"created"`
+	Finished   *time.Time `json:"finished,omitempty"`
+
+	repo repository.Repo
+}
+
+// jobStore keeps the recent jobs and the queue of the jobs to run.
+type jobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string
+	queue chan *Job
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{
+		jobs:  make(map[string]*Job),
+		queue: make(chan *Job, jobQueueSize),
+	}
+}
+
+var errQueueFull = errors.New("job queue is full")
+
+// Adds the job to the queue, evicting the oldest finished jobs.
+func (s *jobStore) enqueue(repo repository.Repo, branches []string) (Job, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return Job{}, err
+	}
+	job := &Job{
+		ID:       hex.EncodeToString(id),
+		Repo:     repo.Name(),
+		Branches: branches,
+		Status:   JobQueued,
+		Created:  time.Now(),
+		repo:     repo,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case s.queue <- job:
+	default:
+		return Job{}, errQueueFull
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	for i := 0; len(s.jobs) > jobRetention && i < len(s.order); {
+		old := s.jobs[s.order[i]]
+		if old.Finished == nil {
+			i++
+			continue
+		}
+		delete(s.jobs, old.ID)
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+	return *job, nil
+}
+
+// Returns a copy of the job.
+func (s *jobStore) get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (s *jobStore) update(id string, fn func(*Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// Marks the job as finished.
+func (s *jobStore) finish(id string, msgs []string, ops int, err error) {
+	s.update(id, func(job *Job) {
+		now := time.Now()
+		job.Finished = &now
+		job.Messages = msgs
+		job.Operations = ops
+		job.Status = JobSucceeded
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+// Runs the workers processing the queued jobs until the watcher stops.
+func (w *Watcher) runWorkers(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case job := <-w.jobs.queue:
+					w.runJob(job)
+				case <-w.RcvDoneCh:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Pulls the branches of the job and waits for the KV update.
+func (w *Watcher) runJob(job *Job) {
+	if !w.Watched(job.repo) {
+		w.jobs.finish(job.ID, nil, 0, ErrRepoRemoved)
+		return
+	}
+	w.jobs.update(job.ID, func(j *Job) { j.Status = JobRunning })
+
+	ops := 0
+	// The new branches are fetched before they are pulled
+	msgs, changed, err := w.fetchRefs(job.repo)
+	pullMsgs, pulled, pullErr := w.pullBranches(job.repo, job.Branches)
+	msgs = append(msgs, pullMsgs...)
+	changed = changed || pulled
+	if err == nil {
+		err = pullErr
+	}
+	if changed {
+		// The KV is updated even when some of the branches failed
+		var kvErr error
+		ops, kvErr = w.notifyAndWait(job.repo, false)
+		if kvErr != nil {
+			msgs = append(msgs, fmt.Sprintf("KV update failed: %s", kvErr))
+			if err == nil {
+				err = kvErr
+			}
+		}
+	}
+	w.jobs.finish(job.ID, msgs, ops, err)
+}
+
+// Notifies the change of the repository and waits for the KV update,
+// returning the number of the applied operations. The forced change pushes
+// the branches even if they are up to date.
+func (w *Watcher) notifyAndWait(repo repository.Repo, force bool) (int, error) {
+	type outcome struct {
+		ops int
+		err error
+	}
+	done := make(chan outcome, 1)
+	change := NewChange(repo)
+	change.Force = force
+	change.onDone(func(ops int, err error) { done <- outcome{ops, err} })
+	w.notify(change)
+	select {
+	case out := <-done:
+		return out.ops, out.err
+	case <-w.RcvDoneCh:
+		return 0, errWatcherStopped
+	}
+}
+
+// Pulls the branches of the repository, and returns the outcome of every
+// branch and whether any of them changed.
+func (w *Watcher) pullBranches(repo repository.Repo, branches []string) ([]string, bool, error) {
+	var msgs []string
+	var failed error
+	changed := false
+	for _, branchName := range branches {
+		w.logger.WithField("repository", repo.Name()).WithField("branchName", branchName).Info("repo found, begin pull")
+		err := w.pull(repo, branchName)
+		switch {
+		case errors.Is(err, git.NoErrAlreadyUpToDate):
+			msg := fmt.Sprintf("Up to date: %s/%s", repo.Name(), branchName)
+			w.logger.Debug(msg)
+			msgs = append(msgs, msg)
+		case err == nil:
+			msg := fmt.Sprintf("Changed: %s/%s", repo.Name(), branchName)
+			w.logger.Info(msg)
+			msgs = append(msgs, msg)
+			changed = true
+		case err != nil:
+			msg := fmt.Sprintf("Failed: %s/%s - %s", repo.Name(), branchName, err)
+			w.logger.Error(msg)
+			msgs = append(msgs, msg)
+			failed = fmt.Errorf("pull of %s/%s failed: %w", repo.Name(), branchName, err)
+		}
+	}
+	return msgs, changed, failed
+}
+
+// HTTP handler reporting the status of a job
+func (w *Watcher) jobHandler(rw http.ResponseWriter, rq *http.Request) {
+	id := mux.Vars(rq)["id"]
+	job, ok := w.jobs.get(id)
+	if !ok {
+		http.Error(rw, fmt.Sprintf("Job %q not found", id), http.StatusNotFound)
+		return
+	}
+	writeJSON(rw, http.StatusOK, job)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v) //nolint:errcheck
+}
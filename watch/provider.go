@@ -0,0 +1,292 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// errNotPush is returned for the events other than push, which are ignored
+	errNotPush          = errors.New("not a push event")
+	errMalformedPayload = errors.New("cannot unmarshal JSON")
+)
+
+// provider parses the push webhooks of a git server.
+type provider interface {
+	// Name of the git server
+	Name() string
+	// Verify verifies the request was sent by the holder of the secret
+	Verify(rq *http.Request, body []byte, secret string) error
+	// Refs returns the refs updated by the push, or errNotPush for the other events
+	Refs(rq *http.Request, body []byte) ([]string, error)
+}
+
+// Returns the providers by the last segment of their webhook path.
+func providers() map[string]provider {
+	return map[string]provider{
+		"github":    githubProvider{},
+		"gitea":     githubProvider{}, // currently gitea webhook payload is compatible with github's
+		"gogs":      gogsProvider{},
+		"stash":     stashProvider{},
+		"bitbucket": bitbucketProvider{},
+		"gitlab":    gitlabProvider{},
+		"azure":     azureProvider{},
+	}
+}
+
+// Checks the event header of the request is the push event.
+func checkEvent(rq *http.Request, header, push string) error {
+	eventType := rq.Header.Get(header)
+	if eventType == "" {
+		return fmt.Errorf("missing %s header", header)
+	}
+	if eventType != push {
+		return errNotPush
+	}
+	return nil
+}
+
+// GithubPayload is the response from GitHub
+type GithubPayload struct {
+	Ref     string `json:"ref"`
+	Deleted bool   `json:"deleted"`
+}
+
+type githubProvider struct{}
+
+func (githubProvider) Name() string { return "GitHub" }
+
+func (githubProvider) Verify(rq *http.Request, body []byte, secret string) error {
+	return verifyGitHub(rq, body, secret)
+}
+
+func (githubProvider) Refs(rq *http.Request, body []byte) ([]string, error) {
+	err := checkEvent(rq, "X-Github-Event", "push")
+	if err != nil {
+		return nil, err
+	}
+	payload := &GithubPayload{}
+	if json.Unmarshal(body, payload) != nil {
+		return nil, errMalformedPayload
+	}
+	// Skip the deleted branch
+	if payload.Deleted {
+		return nil, nil
+	}
+	return []string{payload.Ref}, nil
+}
+
+// Gogs sends the payload of GitHub with its own headers.
+type gogsProvider struct{}
+
+func (gogsProvider) Name() string { return "Gogs" }
+
+// Verifies the X-Gogs-Signature header, the HMAC SHA-256 signature without
+// the algorithm prefix.
+func (gogsProvider) Verify(rq *http.Request, body []byte, secret string) error {
+	return verifyHMAC(rq.Header.Get("X-Gogs-Signature"), body, secret)
+}
+
+func (gogsProvider) Refs(rq *http.Request, body []byte) ([]string, error) {
+	err := checkEvent(rq, "X-Gogs-Event", "push")
+	if err != nil {
+		return nil, err
+	}
+	payload := &GithubPayload{}
+	if json.Unmarshal(body, payload) != nil {
+		return nil, errMalformedPayload
+	}
+	// Skip the deleted branch
+	if payload.Deleted {
+		return nil, nil
+	}
+	return []string{payload.Ref}, nil
+}
+
+// StashPayload is the response from Stash
+type StashPayload struct {
+	RefChanges []struct {
+		RefID string `json:"refId"`
+		Type  string `json:"type"`
+	} `json:"refChanges"`
+}
+
+type stashProvider struct{}
+
+func (stashProvider) Name() string { return "Stash" }
+
+func (stashProvider) Verify(rq *http.Request, body []byte, secret string) error {
+	return verifyBitbucket(rq, body, secret)
+}
+
+func (stashProvider) Refs(rq *http.Request, body []byte) ([]string, error) {
+	payload := &StashPayload{}
+	if json.Unmarshal(body, payload) != nil {
+		return nil, errMalformedPayload
+	}
+	if len(payload.RefChanges) == 0 {
+		return nil, errors.New("refChanges are empty")
+	}
+	refs := make([]string, 0, len(payload.RefChanges))
+	for _, change := range payload.RefChanges {
+		// Skip the deleted branches
+		if change.Type == "DELETE" {
+			continue
+		}
+		refs = append(refs, change.RefID)
+	}
+	return refs, nil
+}
+
+// BitbucketPayload is the response Bitbucket
+type BitbucketPayload struct {
+	Push struct {
+		Changes []struct {
+			// New is null when the branch was deleted
+			New *struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+}
+
+type bitbucketProvider struct{}
+
+func (bitbucketProvider) Name() string { return "Bitbucket" }
+
+func (bitbucketProvider) Verify(rq *http.Request, body []byte, secret string) error {
+	return verifyBitbucket(rq, body, secret)
+}
+
+func (bitbucketProvider) Refs(rq *http.Request, body []byte) ([]string, error) {
+	err := checkEvent(rq, "X-Event-Key", "repo:push")
+	if err != nil {
+		return nil, err
+	}
+	payload := &BitbucketPayload{}
+	if json.Unmarshal(body, payload) != nil {
+		return nil, errMalformedPayload
+	}
+	if len(payload.Push.Changes) == 0 {
+		return nil, errors.New("changes are empty")
+	}
+	var refs []string
+	for _, change := range payload.Push.Changes {
+		// Skip the deleted branches and the tags
+		if change.New == nil || (change.New.Type != "" && change.New.Type != "branch") {
+			continue
+		}
+		// Bitbucket Cloud sends the branch name only
+		ref := change.New.Name
+		if ref != "" && !strings.HasPrefix(ref, GitRefsHeads) {
+			ref = GitRefsHeads + ref
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// GitLabPayload is the response from GitLab
+type GitLabPayload struct {
+	Ref   string `json:"ref"`
+	After string `json:"after"`
+}
+
+type gitlabProvider struct{}
+
+func (gitlabProvider) Name() string { return "GitLab" }
+
+func (gitlabProvider) Verify(rq *http.Request, body []byte, secret string) error {
+	return verifyGitLab(rq, body, secret)
+}
+
+func (gitlabProvider) Refs(rq *http.Request, body []byte) ([]string, error) {
+	err := checkEvent(rq, "X-Gitlab-Event", "Push Hook")
+	if err != nil {
+		return nil, err
+	}
+	payload := &GitLabPayload{}
+	if json.Unmarshal(body, payload) != nil {
+		return nil, errMalformedPayload
+	}
+	// Skip the deleted branch
+	if payload.After == zeroObjectID {
+		return nil, nil
+	}
+	return []string{payload.Ref}, nil
+}
+
+// AzurePayload is the response from Azure DevOps
+type AzurePayload struct {
+	EventType string `json:"eventType"`
+	Resource  struct {
+		RefUpdates []struct {
+			Name        string `json:"name"`
+			NewObjectID string `json:"newObjectId"`
+		} `json:"refUpdates"`
+	} `json:"resource"`
+}
+
+// zeroObjectID is the new object of the deleted refs
+const zeroObjectID = "0000000000000000000000000000000000000000"
+
+type azureProvider struct{}
+
+func (azureProvider) Name() string { return "Azure DevOps" }
+
+// Verifies the password of the basic authentication, which is the only
+// credential the Azure DevOps service hooks send.
+func (azureProvider) Verify(rq *http.Request, body []byte, secret string) error {
+	_, password, ok := rq.BasicAuth()
+	if !ok {
+		return errMissingSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(secret)) != 1 {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+func (azureProvider) Refs(rq *http.Request, body []byte) ([]string, error) {
+	payload := &AzurePayload{}
+	if json.Unmarshal(body, payload) != nil {
+		return nil, errMalformedPayload
+	}
+	// The event type is sent in the payload only
+	if payload.EventType != "git.push" {
+		return nil, errNotPush
+	}
+	if len(payload.Resource.RefUpdates) == 0 {
+		return nil, errors.New("refUpdates are empty")
+	}
+	var refs []string
+	for _, update := range payload.Resource.RefUpdates {
+		// Skip the deleted branches
+		if update.NewObjectID == zeroObjectID {
+			continue
+		}
+		refs = append(refs, update.Name)
+	}
+	return refs, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookAzure(t *testing.T) {
+	repo := newFakeRepo("example", "main", "develop")
+	repo.config.Hooks = []*config.Hook{{Type: "webhook", Secret: "s3cr3t"}}
+	w := newWebhookWatcher(t, repo)
+	auth := map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("git2consul:s3cr3t"))}
+
+	azure := `{
+		"eventType": "git.push",
+		"resource": {"refUpdates": [
+			{"name": "refs/heads/main", "newObjectId": "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+			{"name": "refs/heads/develop", "newObjectId": "0000000000000000000000000000000000000000"},
+			{"name": "refs/tags/v1.0.0", "newObjectId": "4b825dc642cb6eb9a060e54bf8d69288fbee4904"}
+		]}
+	}`
+	rw := sendHook(w.hookHandler(azureProvider{}), "example", azure, nil)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = sendHook(w.hookHandler(azureProvider{}), "example", azure, auth)
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	// The deleted branch and the tag are skipped
+	assert.Equal(t, []string{"main"}, repo.pulls)
+
+	// The other events are ignored
+	rw = sendHook(w.hookHandler(azureProvider{}), "example", `{"eventType": "git.pullrequest.created"}`, auth)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	rw = sendHook(w.hookHandler(azureProvider{}), "example", `{"eventType": "git.push", "resource": {}}`, auth)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"main"}, repo.pulls)
+
+	// The push deleting a branch is accepted without pulling it
+	deletion := `{
+		"eventType": "git.push",
+		"resource": {"refUpdates": [
+			{"name": "refs/heads/develop", "newObjectId": "0000000000000000000000000000000000000000"}
+		]}
+	}`
+	rw = sendHook(w.hookHandler(azureProvider{}), "example", deletion, auth)
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"main"}, repo.pulls)
+}
+
+func TestWebhookGogs(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	repo.config.Hooks = []*config.Hook{{Type: "webhook", Secret: "s3cr3t"}}
+	w := newWebhookWatcher(t, repo)
+
+	body := `{"ref": "refs/heads/main"}`
+	signature := strings.TrimPrefix(sign(body, "s3cr3t"), "sha256=")
+	rw := sendHook(w.hookHandler(gogsProvider{}), "example", body, map[string]string{"X-Gogs-Event": "push"})
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = sendHook(w.hookHandler(gogsProvider{}), "example", body, map[string]string{"X-Gogs-Event": "create", "X-Gogs-Signature": signature})
+	assert.Equal(t, http.StatusOK, rw.Code)
+	rw = sendHook(w.hookHandler(gogsProvider{}), "example", body, map[string]string{"X-Gogs-Signature": signature})
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	rw = sendHook(w.hookHandler(gogsProvider{}), "example", body, map[string]string{"X-Gogs-Event": "push", "X-Gogs-Signature": signature})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"main"}, repo.pulls)
+}
+
+func TestWebhookBranchDeletion(t *testing.T) {
+	zero := "0000000000000000000000000000000000000000"
+	tests := []struct {
+		name     string
+		provider provider
+		body     string
+		headers  map[string]string
+	}{
+		{"github", githubProvider{}, `{"ref": "refs/heads/develop", "deleted": true}`, map[string]string{"X-Github-Event": "push"}},
+		{"gogs", gogsProvider{}, `{"ref": "refs/heads/develop", "deleted": true}`, map[string]string{"X-Gogs-Event": "push"}},
+		{"stash", stashProvider{}, `{"refChanges": [{"refId": "refs/heads/develop", "type": "DELETE"}]}`, nil},
+		{"gitlab", gitlabProvider{}, `{"ref": "refs/heads/develop", "after": "` + zero + `"}`, map[string]string{"X-Gitlab-Event": "Push Hook"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo("example", "main", "develop")
+			w := newWebhookWatcher(t, repo)
+
+			// The push deleting a branch is accepted without pulling it
+			rw := sendHook(w.hookHandler(tt.provider), "example", tt.body, tt.headers)
+			assert.Equal(t, http.StatusAccepted, rw.Code)
+			var job Job
+			assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &job))
+			runJobs(w)
+			assert.Empty(t, repo.pulls)
+			job, _ = w.jobs.get(job.ID)
+			assert.Equal(t, JobSucceeded, job.Status)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// registry indexes the repositories by their name.
+type registry struct {
+	mu    sync.RWMutex
+	repos map[string]repository.Repo
+}
+
+func newRegistry(repos []repository.Repo) *registry {
+	r := &registry{}
+	r.set(repos)
+	return r
+}
+
+// Replaces the registered repositories.
+func (r *registry) set(repos []repository.Repo) {
+	index := make(map[string]repository.Repo, len(repos))
+	for _, repo := range repos {
+		index[repo.Name()] = repo
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.repos = index
+}
+
+// Returns the registered repositories sorted by name.
+func (r *registry) list() []repository.Repo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	repos := make([]repository.Repo, 0, len(r.repos))
+	for _, repo := range r.repos {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name() < repos[j].Name() })
+	return repos
+}
+
+// Returns the repository with the given name.
+func (r *registry) get(name string) (repository.Repo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	repo, ok := r.repos[name]
+	return repo, ok
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"errors"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+// ErrRepoRemoved fails the changes and the jobs of the repositories removed
+// by a reload.
+var ErrRepoRemoved = errors.New("repository removed by reload")
+
+// poller is the interval polling of a repository.
+type poller struct {
+	stop chan struct{}
+	// config the poller was started with
+	config *config.Repo
+}
+
+// Starts the poller of the repository, the caller holds the lock.
+func (w *Watcher) startPoller(repo repository.Repo) {
+	p := &poller{stop: make(chan struct{}), config: repo.GetConfig()}
+	w.pollers[repo.Name()] = p
+	w.wg.Add(1)
+	go w.pollByInterval(repo, p.stop, &w.wg)
+}
+
+// Stops the poller of the repository, the caller holds the lock.
+func (w *Watcher) stopPoller(name string) {
+	if p, ok := w.pollers[name]; ok {
+		close(p.stop)
+		delete(w.pollers, name)
+	}
+}
+
+// Repository returns the watched repository with the given name.
+func (w *Watcher) Repository(name string) (repository.Repo, bool) {
+	return w.registry.get(name)
+}
+
+// Watched returns true if the repository is still watched, and was not
+// removed or replaced by a reload.
+func (w *Watcher) Watched(repo repository.Repo) bool {
+	current, ok := w.registry.get(repo.Name())
+	return ok && current == repo
+}
+
+// Reload replaces the watched repositories without stopping the webhook
+// listener. The repositories missing from the new ones are no longer watched,
+// and their queued jobs and pending changes are dropped. The new repositories,
+// and the ones which were reconfigured or replaced by a different object, are
+// polled with their own configuration and synced to the KV. The other
+// repositories keep running untouched.
+func (w *Watcher) Reload(repos []repository.Repo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := make(map[string]repository.Repo, len(w.Repositories))
+	for _, repo := range w.Repositories {
+		current[repo.Name()] = repo
+	}
+
+	var started []repository.Repo
+	for _, repo := range repos {
+		old, ok := current[repo.Name()]
+		delete(current, repo.Name())
+		switch {
+		case !ok:
+			w.logger.WithField("repository", repo.Name()).Info("Added repository")
+		case old != repo || w.reconfigured(repo):
+			w.logger.WithField("repository", repo.Name()).Info("Reconfigured repository")
+			w.stopPoller(repo.Name())
+		default:
+			continue
+		}
+		started = append(started, repo)
+	}
+	for name := range current {
+		w.logger.WithField("repository", name).Info("Removed repository")
+		w.stopPoller(name)
+	}
+
+	w.Repositories = repos
+	w.registry.set(repos)
+	for _, repo := range started {
+		w.startPoller(repo)
+		// The runner might be busy, so the sync is notified in the background
+		go w.notify(NewChange(repo))
+	}
+}
+
+// Returns true if the configuration of the repository changed since its
+// poller started, the caller holds the lock.
+func (w *Watcher) reconfigured(repo repository.Repo) bool {
+	p, ok := w.pollers[repo.Name()]
+	return ok && p.config != repo.GetConfig()
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	kept, replaced, removed := newFakeRepo("kept", "main"), newFakeRepo("replaced", "main"), newFakeRepo("removed", "main")
+	inPlace := newFakeRepo("in-place", "main")
+	w := New([]repository.Repo{kept, replaced, removed, inPlace}, &config.WebhookServerConfig{}, false)
+	defer w.Stop()
+	w.mu.Lock()
+	for _, repo := range w.Repositories {
+		w.startPoller(repo)
+	}
+	w.mu.Unlock()
+	// The pollers of the repositories without the polling hook return at once
+	w.wg.Wait()
+
+	// The job queued before the removal of its repository is dropped
+	job, err := w.jobs.enqueue(removed, []string{"main"})
+	assert.NoError(t, err)
+
+	reconfigured, added := newFakeRepo("replaced", "main", "develop"), newFakeRepo("added", "main")
+	inPlace.config = &config.Repo{Name: "in-place", Branches: []string{"main", "develop"}}
+	w.Reload([]repository.Repo{kept, reconfigured, added, inPlace})
+
+	// The new and the reconfigured repositories are synced
+	synced := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case change := <-w.RepoChangeCh:
+			synced[change.Repo.Name()] = true
+			assert.NotSame(t, replaced, change.Repo)
+		case <-time.After(time.Second):
+			t.Fatal("The change was not sent")
+		}
+	}
+	assert.Equal(t, map[string]bool{"replaced": true, "added": true, "in-place": true}, synced)
+	assert.Len(t, w.RepoChangeCh, 0)
+
+	runJobs(w)
+	assert.Empty(t, removed.pulls)
+	job, _ = w.jobs.get(job.ID)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Equal(t, ErrRepoRemoved.Error(), job.Error)
+
+	// The changes of the removed repository are dropped
+	var changeErr error
+	change := NewChange(removed)
+	change.onDone(func(_ int, err error) { changeErr = err })
+	w.send(change)
+	assert.ErrorIs(t, changeErr, ErrRepoRemoved)
+	assert.Len(t, w.RepoChangeCh, 0)
+
+	repo, ok := w.Repository("replaced")
+	assert.True(t, ok)
+	assert.Same(t, reconfigured, repo)
+	_, ok = w.Repository("removed")
+	assert.False(t, ok)
+	w.mu.Lock()
+	assert.Len(t, w.pollers, 4)
+	assert.NotContains(t, w.pollers, "removed")
+	w.mu.Unlock()
+}
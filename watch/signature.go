@@ -0,0 +1,107 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+)
+
+var (
+	errMissingSignature = errors.New("missing signature")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+// verifier verifies the webhook request was sent by the holder of the secret.
+type verifier func(rq *http.Request, body []byte, secret string) error
+
+// Returns the secret of the webhook hook of the repository.
+func hookSecret(repo repository.Repo) string {
+	for _, hook := range repo.GetConfig().Hooks {
+		if hook.Type == "webhook" && hook.Secret != "" {
+			return hook.Secret
+		}
+	}
+	return ""
+}
+
+// Verifies the request when the webhook of the repository has a secret,
+// replying 401 on failure.
+func (w *Watcher) authenticate(rw http.ResponseWriter, rq *http.Request, body []byte, repo repository.Repo, verify verifier) bool {
+	secret := hookSecret(repo)
+	if secret == "" {
+		return true
+	}
+	err := verify(rq, body, secret)
+	if err != nil {
+		w.logger.WithField("repository", repo.Name()).WithError(err).Warn("Rejected webhook request")
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// Verifies the X-Hub-Signature-256 header sent by GitHub and Gitea. The
+// older Gitea versions send the X-Gitea-Signature header instead.
+func verifyGitHub(rq *http.Request, body []byte, secret string) error {
+	signature := rq.Header.Get("X-Hub-Signature-256")
+	if signature == "" {
+		signature = rq.Header.Get("X-Gitea-Signature")
+	}
+	return verifyHMAC(signature, body, secret)
+}
+
+// Verifies the X-Hub-Signature header sent by Bitbucket.
+func verifyBitbucket(rq *http.Request, body []byte, secret string) error {
+	return verifyHMAC(rq.Header.Get("X-Hub-Signature"), body, secret)
+}
+
+// Verifies the X-Gitlab-Token header sent by GitLab.
+func verifyGitLab(rq *http.Request, body []byte, secret string) error {
+	token := rq.Header.Get("X-Gitlab-Token")
+	if token == "" {
+		return errMissingSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// Verifies the hex encoded HMAC SHA-256 signature of the body.
+func verifyHMAC(signature string, body []byte, secret string) error {
+	if signature == "" {
+		return errMissingSignature
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return errInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return errInvalidSignature
+	}
+	return nil
+}
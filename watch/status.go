@@ -0,0 +1,181 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// RefReader reads the local branch refs stored in the KV.
+type RefReader interface {
+	KVRef(repo repository.Repo, branchName string) (string, error)
+}
+
+// RepoStatus is the status of a repository served by the status endpoint.
+type RepoStatus struct {
+	Name     string         `json:"name"`
+	Branches []BranchStatus `json:"branches"`
+	// LastSync is the time of the last successful KV update
+	LastSync *time.Time `json:"last_sync,omitempty"`
+	// LastError is the error of the last KV update, if it failed
+	LastError string     `json:"last_error,omitempty"`
+	NextPoll  *time.Time `json:"next_poll,omitempty"`
+}
+
+// BranchStatus is the status of a tracked branch or tag.
+type BranchStatus struct {
+	Name string `json:"name"`
+	// Head is the commit of the local branch or tag
+	Head string `json:"head,omitempty"`
+	// KVRef is the commit recorded in the .ref key of the branch
+	KVRef string `json:"kv_ref,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// repoState is the sync state of a repository.
+type repoState struct {
+	synced   bool
+	lastSync time.Time
+	lastErr  error
+	nextPoll time.Time
+}
+
+// statusStore keeps the sync state of the repositories by their name.
+type statusStore struct {
+	mu    sync.Mutex
+	repos map[string]repoState
+}
+
+func newStatusStore() *statusStore {
+	return &statusStore{repos: make(map[string]repoState)}
+}
+
+// Records the outcome of the KV update of the repository.
+func (s *statusStore) synced(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.repos[name]
+	state.lastErr = err
+	if err == nil {
+		state.synced = true
+		state.lastSync = time.Now()
+	}
+	s.repos[name] = state
+}
+
+// Records the time of the next poll of the repository.
+func (s *statusStore) polled(name string, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.repos[name]
+	state.nextPoll = next
+	s.repos[name] = state
+}
+
+func (s *statusStore) get(name string) repoState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repos[name]
+}
+
+// HTTP handler of the liveness probe
+func (w *Watcher) healthHandler(rw http.ResponseWriter, rq *http.Request) {
+	rw.Write([]byte("ok")) //nolint:errcheck
+}
+
+// HTTP handler of the readiness probe. The watcher is ready when the initial
+// KV update of every repository succeeded and the KV is reachable.
+func (w *Watcher) readyHandler(rw http.ResponseWriter, rq *http.Request) {
+	var reasons []string
+	repos := w.registry.list()
+	for _, repo := range repos {
+		if !w.status.get(repo.Name()).synced {
+			reasons = append(reasons, fmt.Sprintf("%s: initial sync not done", repo.Name()))
+		}
+	}
+	// Reading a ref checks the KV is reachable
+	if len(repos) > 0 && w.KVRefs != nil {
+		if refs, err := repos[0].TrackedRefs(); err == nil && len(refs) > 0 {
+			_, err = w.KVRefs.KVRef(repos[0], refs[0].Short())
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("consul unreachable: %s", err))
+			}
+		}
+	}
+	if len(reasons) > 0 {
+		http.Error(rw, strings.Join(reasons, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+	rw.Write([]byte("ok")) //nolint:errcheck
+}
+
+// HTTP handler reporting the status of the repositories
+func (w *Watcher) statusHandler(rw http.ResponseWriter, rq *http.Request) {
+	repos := w.registry.list()
+	statuses := make([]RepoStatus, 0, len(repos))
+	for _, repo := range repos {
+		statuses = append(statuses, w.repoStatus(repo))
+	}
+	writeJSON(rw, http.StatusOK, map[string]interface{}{"repositories": statuses})
+}
+
+// Returns the status of the repository.
+func (w *Watcher) repoStatus(repo repository.Repo) RepoStatus {
+	state := w.status.get(repo.Name())
+	status := RepoStatus{Name: repo.Name(), Branches: []BranchStatus{}}
+	if !state.lastSync.IsZero() {
+		status.LastSync = &state.lastSync
+	}
+	if state.lastErr != nil {
+		status.LastError = state.lastErr.Error()
+	}
+	if !state.nextPoll.IsZero() {
+		status.NextPoll = &state.nextPoll
+	}
+
+	refs, err := repo.TrackedRefs()
+	if err != nil {
+		w.logger.WithField("repository", repo.Name()).WithError(err).Error("Cannot list the tracked branches")
+	}
+	for _, ref := range refs {
+		branchName := ref.Short()
+		branch := BranchStatus{Name: branchName}
+		var errs []string
+		head, err := repo.ResolveRevision(plumbing.Revision(ref))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("resolving the head failed: %s", err))
+		} else {
+			branch.Head = head.String()
+		}
+		if w.KVRefs != nil {
+			branch.KVRef, err = w.KVRefs.KVRef(repo, branchName)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("reading the KV ref failed: %s", err))
+			}
+		}
+		branch.Error = strings.Join(errs, "; ")
+		status.Branches = append(status.Branches, branch)
+	}
+	return status
+}
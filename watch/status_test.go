@@ -0,0 +1,111 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRefReader serves the refs of the branches by name.
+type fakeRefReader struct {
+	refs map[string]string
+	err  error
+}
+
+func (r *fakeRefReader) KVRef(repo repository.Repo, branchName string) (string, error) {
+	return r.refs[branchName], r.err
+}
+
+func getProbe(handler http.HandlerFunc) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rw
+}
+
+func TestProbes(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	w := newWebhookWatcher(t, repo)
+	refs := &fakeRefReader{}
+	w.KVRefs = refs
+
+	assert.Equal(t, http.StatusOK, getProbe(w.healthHandler).Code)
+
+	// The watcher is not ready until the initial sync succeeded
+	rw := getProbe(w.readyHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Contains(t, rw.Body.String(), "example: initial sync not done")
+
+	repo.kvErr = errors.New("consul is down")
+	w.notifyAndWait(repo, false) //nolint:errcheck
+	assert.Equal(t, http.StatusServiceUnavailable, getProbe(w.readyHandler).Code)
+
+	repo.kvErr = nil
+	w.notifyAndWait(repo, false) //nolint:errcheck
+	assert.Equal(t, http.StatusOK, getProbe(w.readyHandler).Code)
+
+	// A failed update does not make the watcher unready, an unreachable KV does
+	repo.kvErr = errors.New("consul is down")
+	w.notifyAndWait(repo, false) //nolint:errcheck
+	assert.Equal(t, http.StatusOK, getProbe(w.readyHandler).Code)
+	refs.err = errors.New("connection refused")
+	rw = getProbe(w.readyHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Contains(t, rw.Body.String(), "consul unreachable: connection refused")
+}
+
+func TestStatus(t *testing.T) {
+	repo := newFakeRepo("example", "main", "develop")
+	w := newWebhookWatcher(t, repo, newFakeRepo("alpha", "main"))
+	w.KVRefs = &fakeRefReader{refs: map[string]string{"main": fakeCommit}}
+
+	w.notifyAndWait(repo, false) //nolint:errcheck
+	next := time.Now().Add(time.Minute)
+	w.status.polled("example", next)
+	repo.kvErr = errors.New("consul is down")
+	w.notifyAndWait(repo, false) //nolint:errcheck
+
+	rw := getProbe(w.statusHandler)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var status struct {
+		Repositories []RepoStatus `json:"repositories"`
+	}
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+	assert.Len(t, status.Repositories, 2)
+
+	alpha := status.Repositories[0]
+	assert.Equal(t, "alpha", alpha.Name)
+	assert.Nil(t, alpha.LastSync)
+	assert.Nil(t, alpha.NextPoll)
+
+	example := status.Repositories[1]
+	assert.Equal(t, "example", example.Name)
+	assert.Equal(t, []BranchStatus{
+		{Name: "main", Head: fakeCommit, KVRef: fakeCommit},
+		{Name: "develop", Head: fakeCommit},
+	}, example.Branches)
+	assert.NotNil(t, example.LastSync)
+	assert.Equal(t, "consul is down", example.LastError)
+	assert.True(t, next.Equal(*example.NextPoll))
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/gorilla/mux"
+)
+
+// SyncResult is the reply of the sync endpoint.
+type SyncResult struct {
+	Repo string `json:"repository"`
+	// Commits maps the synced branches and tags to their commit after the pull
+	Commits    map[string]string `json:"commits"`
+	Operations int               `json:"operations"`
+	Messages   []string          `json:"messages,omitempty"`
+}
+
+// Verifies the bearer token of the request, replying 403 when the token of
+// the webhook server is not set and 401 when the request does not carry it.
+func (w *Watcher) authorizeSync(rw http.ResponseWriter, rq *http.Request) bool {
+	token := w.hookSvr.Token
+	if token == "" {
+		http.Error(rw, "Sync is disabled, the webhook token is not set", http.StatusForbidden)
+		return false
+	}
+	auth := rq.Header.Get("Authorization")
+	bearer := strings.TrimPrefix(auth, "Bearer ")
+	if bearer == auth || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+		w.logger.WithField("repository", mux.Vars(rq)["repository"]).Warn("Rejected sync request")
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(rw, "invalid bearer token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// HTTP handler pulling the branches of the repository, or the branch given
+// by the branch parameter, and waiting for the KV update.
+func (w *Watcher) syncHandler(rw http.ResponseWriter, rq *http.Request) {
+	if !w.authorizeSync(rw, rq) {
+		return
+	}
+	repo := w.lookupRepo(rw, mux.Vars(rq)["repository"])
+	if repo == nil {
+		return
+	}
+
+	// The new branches and tags are fetched before the tracked refs are listed
+	msgs, _, err := w.fetchRefs(repo)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refs, err := repo.TrackedRefs()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var branches []string
+	for _, ref := range refs {
+		if ref.IsBranch() {
+			branches = append(branches, ref.Short())
+		}
+	}
+	if branch := rq.URL.Query().Get("branch"); branch != "" {
+		if !repository.StringInSlice(branch, branches) {
+			http.Error(rw, fmt.Sprintf("Branch %q is not tracked", branch), http.StatusBadRequest)
+			return
+		}
+		branches = []string{branch}
+		refs = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(branch)}
+	}
+
+	pullMsgs, _, err := w.pullBranches(repo, branches)
+	msgs = append(msgs, pullMsgs...)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The branches are pushed even when they are up to date, so the sync
+	// repairs a KV which drifted from the repository
+	ops, err := w.notifyAndWait(repo, true)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("KV update failed: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	result := SyncResult{Repo: repo.Name(), Commits: make(map[string]string), Operations: ops, Messages: msgs}
+	for _, ref := range refs {
+		hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("Cannot resolve the commit of %s/%s: %s", repo.Name(), ref.Short(), err), http.StatusInternalServerError)
+			return
+		}
+		result.Commits[ref.Short()] = hash.String()
+	}
+	writeJSON(rw, http.StatusOK, result)
+}
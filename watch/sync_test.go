@@ -0,0 +1,84 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSync(t *testing.T) {
+	repo := newFakeRepo("example", "main", "develop")
+	repo.ops = 3
+	w := newWebhookWatcher(t, repo)
+	sync := func(name, query, token string) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest(http.MethodPost, "/"+name+"/sync"+query, nil)
+		if token != "" {
+			rq.Header.Set("Authorization", "Bearer "+token)
+		}
+		rq = mux.SetURLVars(rq, map[string]string{"repository": name})
+		rw := httptest.NewRecorder()
+		w.syncHandler(rw, rq)
+		return rw
+	}
+
+	// The sync is disabled without token
+	rw := sync("example", "", "s3cret")
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+
+	w.hookSvr.Token = "s3cret"
+	rw = sync("example", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = sync("example", "", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	assert.Empty(t, repo.pulls)
+
+	rw = sync("unknown", "", "s3cret")
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	rw = sync("example", "?branch=feature", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+
+	rw = sync("example", "?branch=develop", "s3cret")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var result SyncResult
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &result))
+	assert.Equal(t, "example", result.Repo)
+	assert.Equal(t, map[string]string{"develop": fakeCommit}, result.Commits)
+	assert.Equal(t, 3, result.Operations)
+	assert.Equal(t, []string{"develop"}, repo.pulls)
+
+	// Every tracked branch is synced without the branch parameter
+	repo.pulls = nil
+	rw = sync("example", "", "s3cret")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &result))
+	assert.Equal(t, map[string]string{"main": fakeCommit, "develop": fakeCommit}, result.Commits)
+	assert.Equal(t, []string{"main", "develop"}, repo.pulls)
+	// The sync pushes the branches even if they are up to date
+	assert.Equal(t, 2, repo.forced)
+
+	repo.kvErr = errors.New("consul is down")
+	rw = sync("example", "", "s3cret")
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	assert.Contains(t, rw.Body.String(), "consul is down")
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/apex/log"
+)
+
+// certReloader holds the certificate and the client CAs of the webhook
+// listener, and reloads them when the modification time of their files
+// changes.
+type certReloader struct {
+	cfg    config.WebhookTLSConfig
+	logger *log.Entry
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  []time.Time
+}
+
+func newCertReloader(cfg config.WebhookTLSConfig) (*certReloader, error) {
+	r := &certReloader{
+		cfg:    cfg,
+		logger: log.WithField("caller", "webhook-tls"),
+	}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	err = r.load(modTimes)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Returns the files of the configuration.
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// Returns the modification times of the files.
+func (r *certReloader) stat() ([]time.Time, error) {
+	files := r.files()
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// Loads the files, the caller holds the lock unless the reloader is new.
+func (r *certReloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading the webhook certificate failed: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("loading the webhook client CA failed: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in the webhook client CA %s", r.cfg.ClientCAFile)
+		}
+	}
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return nil
+}
+
+// Reloads the files when any of them changed. On failure the files loaded
+// last are kept, so a certificate renewal writing the files one at a time
+// does not break the listener.
+func (r *certReloader) reload() {
+	modTimes, err := r.stat()
+	if err != nil {
+		r.logger.WithError(err).Warn("Cannot check the webhook TLS files")
+		return
+	}
+	for i := range modTimes {
+		if !modTimes[i].Equal(r.modTimes[i]) {
+			err = r.load(modTimes)
+			if err != nil {
+				r.logger.WithError(err).Warn("Cannot reload the webhook TLS files")
+				return
+			}
+			r.logger.Info("Reloaded the webhook TLS files")
+			return
+		}
+	}
+}
+
+// Returns the TLS configuration of the handshake with the files loaded last.
+func (r *certReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reload()
+	cfg := &tls.Config{
+		MinVersion:   r.minVersion(),
+		Certificates: []tls.Certificate{*r.cert},
+	}
+	if r.clientCAs != nil {
+		cfg.ClientCAs = r.clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+func (r *certReloader) minVersion() uint16 {
+	version, _ := config.TLSVersion(r.cfg.MinVersion)
+	return version
+}
+
+// Returns the TLS configuration of the webhook listener.
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         r.minVersion(),
+		GetConfigForClient: r.getConfigForClient,
+		// Unused when GetConfigForClient is set, it keeps the server from loading the files itself
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return r.cert, nil
+		},
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate signed by its parent, or self-signed without parent.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "git2consul"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// Writes the certificate and its key, moving their modification time forward.
+func (c *testCert) write(t *testing.T, certFile, keyFile string, modTime time.Time) {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, c.pem, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func (c *testCert) keyPair(t *testing.T) tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestWebhookTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, 1, nil)
+	client := newTestCert(t, 2, ca)
+	server := newTestCert(t, 3, ca)
+	cfg := config.WebhookTLSConfig{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		MinVersion:   "1.2",
+	}
+	server.write(t, cfg.CertFile, cfg.KeyFile, time.Now().Add(-time.Minute))
+	require.NoError(t, os.WriteFile(cfg.ClientCAFile, ca.pem, 0o600))
+
+	reloader, err := newCertReloader(cfg)
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", reloader.tlsConfig())
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {}), ReadHeaderTimeout: time.Second}
+	go srv.Serve(listener) //nolint:errcheck
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	// Returns the serial number of the certificate of the server
+	handshake := func(cfg *tls.Config) (int64, error) {
+		cfg.RootCAs = roots
+		conn, err := tls.Dial("tcp", listener.Addr().String(), cfg)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		// The client learns the server rejected its certificate on the first read with TLS 1.3
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: git2consul\r\n\r\n"))
+		if err == nil {
+			_, err = conn.Read(make([]byte, 1))
+		}
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), err
+	}
+
+	// The client certificate is required
+	_, err = handshake(&tls.Config{})
+	assert.Error(t, err)
+	serial, err := handshake(&tls.Config{Certificates: []tls.Certificate{client.keyPair(t)}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), serial)
+
+	// The renewed certificate is served without restart
+	renewed := newTestCert(t, 4, ca)
+	renewed.write(t, cfg.CertFile, cfg.KeyFile, time.Now())
+	serial, err = handshake(&tls.Config{Certificates: []tls.Certificate{client.keyPair(t)}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), serial)
+
+	// A broken certificate keeps the one loaded last
+	require.NoError(t, os.WriteFile(cfg.KeyFile, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(cfg.KeyFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	serial, err = handshake(&tls.Config{Certificates: []tls.Certificate{client.keyPair(t)}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), serial)
+}
+
+func TestWebhookTLSMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, 1, nil)
+	cfg := config.WebhookTLSConfig{
+		CertFile:   filepath.Join(dir, "tls.crt"),
+		KeyFile:    filepath.Join(dir, "tls.key"),
+		MinVersion: "1.3",
+	}
+	ca.write(t, cfg.CertFile, cfg.KeyFile, time.Now())
+
+	reloader, err := newCertReloader(cfg)
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", reloader.tlsConfig())
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake() //nolint:errcheck
+			conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS12})
+	if err == nil {
+		conn.Close()
+	}
+	assert.Error(t, err)
+	conn, err = tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: roots})
+	assert.NoError(t, err)
+	if err == nil {
+		assert.Equal(t, uint16(tls.VersionTLS13), conn.ConnectionState().Version)
+		conn.Close()
+	}
+
+	// The missing files fail the listener
+	cfg.KeyFile = filepath.Join(dir, "missing.key")
+	_, err = newCertReloader(cfg)
+	assert.Error(t, err)
+}
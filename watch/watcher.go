@@ -17,20 +17,38 @@ limitations under the License.
 package watch
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/metrics"
 	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/apex/log"
+	"github.com/go-git/go-git/v5"
 )
 
 // Watcher is used to keep track of changes of the repositories
 type Watcher struct {
 	logger *log.Entry
 
+	// mu guards the repositories and their pollers, which change on reload
+	mu           sync.Mutex
 	Repositories []repository.Repo
-
-	RepoChangeCh chan repository.Repo
+	pollers      map[string]*poller
+	wg           sync.WaitGroup
+	registry     *registry
+	jobs         *jobStore
+	debouncer    *debouncer
+	status       *statusStore
+
+	// KVRefs reads the refs stored in the KV for the status endpoints
+	KVRefs RefReader
+	// Metrics records the pulls and the webhook requests
+	Metrics *metrics.Metrics
+
+	RepoChangeCh chan *Change
 	ErrCh        chan error
 	RcvDoneCh    chan struct{}
 	SndDoneCh    chan struct{}
@@ -42,11 +60,16 @@ type Watcher struct {
 // New create a new watcher, passing in the repositories, webhook
 // listener config, and optional once flag
 func New(repos []repository.Repo, hookSvr *config.WebhookServerConfig, once bool) *Watcher {
-	repoChangeCh := make(chan repository.Repo, len(repos))
+	repoChangeCh := make(chan *Change, len(repos))
 	logger := log.WithField("caller", "watcher")
 
 	return &Watcher{
 		Repositories: repos,
+		pollers:      make(map[string]*poller),
+		registry:     newRegistry(repos),
+		jobs:         newJobStore(),
+		debouncer:    newDebouncer(),
+		status:       newStatusStore(),
 		RepoChangeCh: repoChangeCh,
 		ErrCh:        make(chan error),
 		RcvDoneCh:    make(chan struct{}, 1),
@@ -61,23 +84,23 @@ func New(repos []repository.Repo, hookSvr *config.WebhookServerConfig, once bool
 func (w *Watcher) Watch() {
 	defer close(w.SndDoneCh)
 
+	w.mu.Lock()
 	// Pass repositories to RepoChangeCh for initial update to the KV
 	for _, repo := range w.Repositories {
-		w.RepoChangeCh <- repo
+		w.send(NewChange(repo))
 	}
 
-	// WaitGroup size is equal to number of interval goroutine plus webhook goroutine
-	var wg sync.WaitGroup
-	wg.Add(len(w.Repositories) + 1)
-
+	// WaitGroup counts the interval goroutines plus the webhook goroutine
 	for _, repo := range w.Repositories {
-		go w.pollByInterval(repo, &wg)
+		w.startPoller(repo)
 	}
+	w.mu.Unlock()
 
-	go w.pollByWebhook(&wg)
+	w.wg.Add(1)
+	go w.pollByWebhook(&w.wg)
 
 	go func() {
-		wg.Wait()
+		w.wg.Wait()
 		// Only exit if it's -once, otherwise there might be webhook polling
 		if w.once {
 			w.Stop()
@@ -91,7 +114,7 @@ func (w *Watcher) Watch() {
 			log.WithError(err).Error("Watcher error")
 		case <-w.RcvDoneCh:
 			w.logger.Info("Received finish")
-			wg.Wait()
+			w.wg.Wait()
 			return
 		}
 	}
@@ -102,3 +125,37 @@ func (w *Watcher) Stop() {
 	w.logger.Info("Stopping watcher...")
 	close(w.RcvDoneCh)
 }
+
+// Pulls the branch of the repository, recording the outcome and the duration
+// of the pull.
+func (w *Watcher) pull(repo repository.Repo, branchName string) error {
+	start := time.Now()
+	err := repo.Pull(branchName)
+	outcome := metrics.PullChanged
+	switch {
+	case errors.Is(err, git.NoErrAlreadyUpToDate):
+		outcome = metrics.PullUpToDate
+	case err != nil:
+		outcome = metrics.PullFailed
+	}
+	w.Metrics.ObservePull(repo.Name(), branchName, outcome, time.Since(start))
+	return err
+}
+
+// Fetches the new tracked branches and the new or moved tracked tags of the
+// repository, returns the messages and whether any ref was fetched.
+func (w *Watcher) fetchRefs(repo repository.Repo) ([]string, bool, error) {
+	refs, err := repo.FetchRefs()
+	if err != nil {
+		msg := fmt.Sprintf("Failed: %s - %s", repo.Name(), err)
+		w.logger.Error(msg)
+		return []string{msg}, false, fmt.Errorf("fetch of %s failed: %w", repo.Name(), err)
+	}
+	msgs := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		msg := fmt.Sprintf("Fetched: %s/%s", repo.Name(), ref.Short())
+		w.logger.Info(msg)
+		msgs = append(msgs, msg)
+	}
+	return msgs, len(refs) > 0, nil
+}
@@ -17,50 +17,21 @@ limitations under the License.
 package watch
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"sort"
+	"strings"
 	"sync"
 
 	"github.com/apex/log"
 
-	"github.com/go-git/go-git/v5"
+	"github.com/KohlsTechnology/git2consul-go/repository"
 	"github.com/gorilla/mux"
 )
 
 const GitRefsHeads = "refs/heads/"
 
-// GithubPayload is the response from GitHub
-type GithubPayload struct {
-	Ref string `json:"ref"`
-}
-
-// StashPayload is the response from Stash
-type StashPayload struct {
-	RefChanges []struct {
-		RefID string `json:"refId"`
-	} `json:"refChanges"`
-}
-
-// BitbucketPayload is the response Bitbucket
-type BitbucketPayload struct {
-	Push struct {
-		Changes []struct {
-			New struct {
-				Name string `json:"name"`
-			} `json:"new"`
-		} `json:"changes"`
-	} `json:"push"`
-}
-
-// GitLabPayload is the response from GitLab
-type GitLabPayload struct {
-	Ref string `json:"ref"`
-}
-
 func (w *Watcher) pollByWebhook(wg *sync.WaitGroup) {
 	defer wg.Done()
 
@@ -68,6 +39,8 @@ func (w *Watcher) pollByWebhook(wg *sync.WaitGroup) {
 		return
 	}
 
+	w.runWorkers(w.hookSvr.Workers)
+
 	errCh := make(chan error, 1)
 	// Passing errCh instead of w.ErrCh to better handle watcher termination
 	// since the caller can't determine what type of error it receives from watcher
@@ -87,265 +60,140 @@ func (w *Watcher) pollByWebhook(wg *sync.WaitGroup) {
 // ListenAndServe starts the listener server for hooks
 func (w *Watcher) ListenAndServe(errCh chan<- error) {
 	r := mux.NewRouter()
-	r.HandleFunc("/{repository}/github", w.githubHandler)
-	r.HandleFunc("/{repository}/gitea", w.githubHandler)
-	r.HandleFunc("/{repository}/stash", w.stashHandler)
-	r.HandleFunc("/{repository}/bitbucket", w.bitbucketHandler)
-	r.HandleFunc("/{repository}/gitlab", w.gitlabHandler)
-
-	addr := fmt.Sprintf("%s:%d", w.hookSvr.Address, w.hookSvr.Port)
-	log.Infof("webhook http server listening on %s", addr)
-	errCh <- http.ListenAndServe(addr, r)
-}
-
-// HTTP handler for github, and also gitea (currently gitea webhook payload is compatible with github's)
-func (w *Watcher) githubHandler(rw http.ResponseWriter, rq *http.Request) {
-	vars := mux.Vars(rq)
-	repository := vars["repository"]
-	eventType := rq.Header.Get("X-Github-Event")
-
-	w.logger.WithField("repository", repository).WithField("eventType", eventType).Info("Received hook event from GitHub")
-
-	if eventType == "" {
-		http.Error(rw, "Missing X-Github-Event header", http.StatusBadRequest)
-		return
-	}
-	// Only process pusn events
-	if eventType != "push" {
-		return
-	}
-
-	body, err := io.ReadAll(rq.Body)
-	if err != nil {
-		http.Error(rw, "Cannot read body", http.StatusInternalServerError)
-		return
-	}
-
-	payload := &GithubPayload{}
-	err = json.Unmarshal(body, payload)
-	if err != nil {
-		http.Error(rw, "Cannot unmarshal JSON", http.StatusInternalServerError)
-		return
-	}
-
-	// Check the content
-	ref := payload.Ref
-	if ref == "" {
-		http.Error(rw, "ref is empty", http.StatusInternalServerError)
-		return
+	r.HandleFunc("/healthz", w.healthHandler).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", w.readyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/status", w.statusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/jobs/{id}", w.jobHandler).Methods(http.MethodGet)
+	r.HandleFunc("/{repository}/sync", w.syncHandler).Methods(http.MethodPost)
+	if w.Metrics != nil {
+		r.Handle("/metrics", w.Metrics.Handler()).Methods(http.MethodGet)
 	}
-
-	if len(ref) <= 11 || ref[:11] != GitRefsHeads {
-		return
+	for path, p := range providers() {
+		r.HandleFunc("/{repository}/"+path, w.instrument(path, w.hookHandler(p)))
 	}
 
-	branchName := ref[11:]
-
-	i := sort.Search(len(w.Repositories), func(i int) bool {
-		return w.Repositories[i].Name() == repository
-	})
-
-	// sort.Search could return last index if not found, so need to check once more
-	if i == len(w.Repositories) || w.Repositories[i].Name() != repository {
+	addr := fmt.Sprintf("%s:%d", w.hookSvr.Address, w.hookSvr.Port)
+	server := &http.Server{Addr: addr, Handler: r}
+	if w.hookSvr.TLS.Enabled() {
+		reloader, err := newCertReloader(w.hookSvr.TLS)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		server.TLSConfig = reloader.tlsConfig()
+		log.Infof("webhook https server listening on %s", addr)
+		errCh <- server.ListenAndServeTLS("", "")
 		return
 	}
-
-	repo := w.Repositories[i]
-	w.logger.WithField("repository", repo.Name()).WithField("branchName", branchName).Info("repo found, begin pull")
-
-	err = repo.Pull(branchName)
-	switch {
-	case errors.Is(err, git.NoErrAlreadyUpToDate):
-		msg := fmt.Sprintf("Up to date: %s/%s", repo.Name(), branchName)
-		w.logger.Debug(msg)
-		rw.Write([]byte(msg))
-	case err == nil:
-		msg := fmt.Sprintf("Changed: %s/%s", repo.Name(), branchName)
-		w.logger.Info(msg)
-		rw.Write([]byte(msg))
-		w.RepoChangeCh <- repo
-	case err != nil:
-		msg := fmt.Sprintf("Failed: %s/%s - %s", repo.Name(), branchName, err)
-		w.logger.Error(msg)
-		rw.WriteHeader(http.StatusInternalServerError)
-		rw.Write([]byte(msg))
-	}
+	log.Infof("webhook http server listening on %s", addr)
+	errCh <- server.ListenAndServe()
 }
 
-// HTTP handler for Stash
-func (w *Watcher) stashHandler(rw http.ResponseWriter, rq *http.Request) {
-	vars := mux.Vars(rq)
-	repository := vars["repository"]
-
-	body, err := io.ReadAll(rq.Body)
-	if err != nil {
-		http.Error(rw, "Cannot read body", http.StatusInternalServerError)
-		return
-	}
-
-	payload := &StashPayload{}
-	err = json.Unmarshal(body, payload)
-	if err != nil {
-		http.Error(rw, "Cannot unmarshal JSON", http.StatusInternalServerError)
-		return
-	}
-
-	ref := payload.RefChanges[0].RefID
-
-	if ref == "" {
-		http.Error(rw, "ref is empty", http.StatusInternalServerError)
-		return
-	}
-	if len(ref) <= 11 || ref[:11] != GitRefsHeads {
-		return
-	}
-
-	branchName := ref[11:]
-
-	i := sort.Search(len(w.Repositories), func(i int) bool {
-		return w.Repositories[i].Name() == repository
-	})
-
-	// sort.Search could return last index if not found, so need to check once more
-	if i == len(w.Repositories) || w.Repositories[i].Name() != repository {
-		return
-	}
-
-	repo := w.Repositories[i]
-	w.logger.WithField("repository", repo.Name()).Info("Received hook event from Stash")
-	err = repo.Pull(branchName)
-	switch {
-	case errors.Is(err, git.NoErrAlreadyUpToDate):
-		w.logger.Debugf("Up to date: %s/%s", repo.Name(), branchName)
-	case err == nil:
-		w.logger.Infof("Changed: %s/%s", repo.Name(), branchName)
-		w.RepoChangeCh <- repo
-	case err != nil:
-		w.logger.Errorf("Failed: %s/%s - %s", repo.Name(), branchName, err)
+// Returns the repository with the given name. If there is none, it replies
+// 404 and returns nil.
+func (w *Watcher) lookupRepo(rw http.ResponseWriter, name string) repository.Repo {
+	repo, ok := w.registry.get(name)
+	if !ok {
+		w.logger.WithField("repository", name).Warn("Received hook event for unknown repository")
+		http.Error(rw, fmt.Sprintf("Repository %q not found", name), http.StatusNotFound)
+		return nil
 	}
+	return repo
 }
 
-// HTTP handler for Bitbucket
-func (w *Watcher) bitbucketHandler(rw http.ResponseWriter, rq *http.Request) {
-	vars := mux.Vars(rq)
-	repository := vars["repository"]
-
-	eventType := rq.Header.Get("X-Event-Key")
-	if eventType == "" {
-		http.Error(rw, "Missing X-Event-key header", http.StatusBadRequest)
-		return
-	}
-	// Only process pusn events
-	if eventType != "repo:push" {
-		return
-	}
-
-	body, err := io.ReadAll(rq.Body)
-	if err != nil {
-		http.Error(rw, "Cannot read body", http.StatusInternalServerError)
-		return
-	}
+// statusRecorder records the status code written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
 
-	payload := &BitbucketPayload{}
-	err = json.Unmarshal(body, payload)
-	if err != nil {
-		http.Error(rw, "Cannot unmarshal JSON", http.StatusInternalServerError)
-		return
-	}
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
 
-	// Check the content
-	ref := payload.Push.Changes[0].New.Name
-	if ref == "" {
-		http.Error(rw, "ref is empty", http.StatusInternalServerError)
-		return
-	}
-	if len(ref) <= 11 || ref[:11] != GitRefsHeads {
-		return
+// Wraps the handler of the webhooks of the provider, recording the status
+// codes of its replies.
+func (w *Watcher) instrument(provider string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, rq *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: rw, code: http.StatusOK}
+		handler(recorder, rq)
+		w.Metrics.ObserveWebhook(provider, recorder.code)
 	}
+}
 
-	branchName := ref[11:]
+// Returns the HTTP handler of the push webhooks of the provider.
+func (w *Watcher) hookHandler(p provider) http.HandlerFunc {
+	return func(rw http.ResponseWriter, rq *http.Request) {
+		body, err := io.ReadAll(rq.Body)
+		if err != nil {
+			http.Error(rw, "Cannot read body", http.StatusInternalServerError)
+			return
+		}
 
-	i := sort.Search(len(w.Repositories), func(i int) bool {
-		return w.Repositories[i].Name() == repository
-	})
+		repo := w.lookupRepo(rw, mux.Vars(rq)["repository"])
+		if repo == nil {
+			return
+		}
+		if !w.authenticate(rw, rq, body, repo, p.Verify) {
+			return
+		}
 
-	// sort.Search could return last index if not found, so need to check once more
-	if i == len(w.Repositories) || w.Repositories[i].Name() != repository {
-		return
-	}
+		refs, err := p.Refs(rq, body)
+		if errors.Is(err, errNotPush) {
+			return
+		}
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	repo := w.Repositories[i]
-	w.logger.WithField("repository", repo.Name()).Info("Received hook event from Bitbucket")
-	err = repo.Pull(branchName)
-	switch {
-	case errors.Is(err, git.NoErrAlreadyUpToDate):
-		w.logger.Debugf("Up to date: %s/%s", repo.Name(), branchName)
-	case err == nil:
-		w.logger.Infof("Changed: %s/%s", repo.Name(), branchName)
-		w.RepoChangeCh <- repo
-	case err != nil:
-		w.logger.Errorf("Failed: %s/%s - %s", repo.Name(), branchName, err)
+		w.logger.WithField("repository", repo.Name()).Infof("Received hook event from %s", p.Name())
+		w.pullRefs(rw, repo, refs)
 	}
 }
 
-func (w *Watcher) gitlabHandler(rw http.ResponseWriter, rq *http.Request) {
-	vars := mux.Vars(rq)
-	repository := vars["repository"]
-
-	eventType := rq.Header.Get("X-Gitlab-Event")
-	if eventType == "" {
-		http.Error(rw, "Missing X-Gitlab-Event header", http.StatusBadRequest)
-		return
-	}
-	// Only process pusn events
-	if eventType != "Push Hook" {
-		return
+// Returns the tracked branches updated by the refs, without duplicates. An
+// error is returned if any of the refs is empty. There are no refs when the
+// push only deleted branches or moved tags.
+func trackedBranches(repo repository.Repo, refs []string) ([]string, error) {
+	var branches []string
+	for _, ref := range refs {
+		if ref == "" {
+			return nil, errors.New("ref is empty")
+		}
+		// Skip the other refs, the new tags are fetched by the job
+		if !strings.HasPrefix(ref, GitRefsHeads) || len(ref) == len(GitRefsHeads) {
+			continue
+		}
+		branchName := strings.TrimPrefix(ref, GitRefsHeads)
+		if !repository.MatchRef(repo.GetConfig().Branches, branchName) {
+			continue
+		}
+		if !repository.StringInSlice(branchName, branches) {
+			branches = append(branches, branchName)
+		}
 	}
+	return branches, nil
+}
 
-	body, err := io.ReadAll(rq.Body)
+// Queues the job pulling the tracked branches updated by the refs, and
+// replies 202 with the job. The job is queued even if no tracked branch was
+// updated, so it fetches the pushed tags.
+func (w *Watcher) pullRefs(rw http.ResponseWriter, repo repository.Repo, refs []string) {
+	branches, err := trackedBranches(repo, refs)
 	if err != nil {
-		http.Error(rw, "Cannot read body", http.StatusInternalServerError)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	payload := &GitLabPayload{}
-	err = json.Unmarshal(body, payload)
+	job, err := w.jobs.enqueue(repo, branches)
 	if err != nil {
-		http.Error(rw, "Cannot unmarshal JSON", http.StatusInternalServerError)
-		return
-	}
-
-	// Check the content
-	ref := payload.Ref
-	if ref == "" {
-		http.Error(rw, "ref is empty", http.StatusInternalServerError)
-		return
-	}
-	if len(ref) <= 11 || ref[:11] != GitRefsHeads {
+		w.logger.WithField("repository", repo.Name()).WithError(err).Error("Cannot queue the job")
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
 		return
 	}
-
-	branchName := ref[11:]
-
-	i := sort.Search(len(w.Repositories), func(i int) bool {
-		return w.Repositories[i].Name() == repository
-	})
-
-	// sort.Search could return last index if not found, so need to check once more
-	if i == len(w.Repositories) || w.Repositories[i].Name() != repository {
-		return
-	}
-
-	repo := w.Repositories[i]
-	w.logger.WithField("repository", repo.Name()).Info("Received hook event from GitLab")
-	err = repo.Pull(branchName)
-	switch {
-	case errors.Is(err, git.NoErrAlreadyUpToDate):
-		w.logger.Debugf("Up to date: %s/%s", repo.Name(), branchName)
-	case err == nil:
-		w.logger.Infof("Changed: %s/%s", repo.Name(), branchName)
-		w.RepoChangeCh <- repo
-	case err != nil:
-		w.logger.Errorf("Failed: %s/%s - %s", repo.Name(), branchName, err)
-	}
+	w.logger.WithField("repository", repo.Name()).WithField("job", job.ID).Infof("Queued the job of branches %v", branches)
+	rw.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(rw, http.StatusAccepted, job)
 }
@@ -0,0 +1,352 @@
+/*
+Copyright 2019 Kohl's Department Stores, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KohlsTechnology/git2consul-go/config"
+	"github.com/KohlsTechnology/git2consul-go/metrics"
+	"github.com/KohlsTechnology/git2consul-go/repository"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepo records the pulled branches.
+type fakeRepo struct {
+	repository.Repo
+	name     string
+	config   *config.Repo
+	pulls    []string
+	fetched  []plumbing.ReferenceName
+	fetchErr error
+	changes  int
+	forced   int
+	ops      int
+	kvErr    error
+}
+
+func (r *fakeRepo) Name() string             { return r.name }
+func (r *fakeRepo) GetConfig() *config.Repo  { return r.config }
+func (r *fakeRepo) Pull(branch string) error { r.pulls = append(r.pulls, branch); return nil }
+
+// The tracked refs of the fake repository are its configured branches.
+func (r *fakeRepo) TrackedRefs() ([]plumbing.ReferenceName, error) {
+	refs := make([]plumbing.ReferenceName, 0, len(r.config.Branches))
+	for _, branch := range r.config.Branches {
+		refs = append(refs, plumbing.NewBranchReferenceName(branch))
+	}
+	return refs, nil
+}
+
+// The fake repository fetches the refs set by the test once.
+func (r *fakeRepo) FetchRefs() ([]plumbing.ReferenceName, error) {
+	fetched := r.fetched
+	r.fetched = nil
+	return fetched, r.fetchErr
+}
+
+// fakeCommit is the commit every branch of the fake repository resolves to.
+const fakeCommit = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+func (r *fakeRepo) ResolveRevision(plumbing.Revision) (*plumbing.Hash, error) {
+	hash := plumbing.NewHash(fakeCommit)
+	return &hash, nil
+}
+
+func newFakeRepo(name string, branches ...string) *fakeRepo {
+	return &fakeRepo{name: name, config: &config.Repo{Name: name, Branches: branches}}
+}
+
+// Creates the watcher with a consumer of the changes which counts them.
+func newWebhookWatcher(t *testing.T, repos ...repository.Repo) *Watcher {
+	w := New(repos, &config.WebhookServerConfig{}, false)
+	go func() {
+		for {
+			select {
+			case change := <-w.RepoChangeCh:
+				repo := change.Repo.(*fakeRepo)
+				repo.changes++
+				if change.Force {
+					repo.forced++
+				}
+				change.Done(repo.ops, repo.kvErr)
+			case <-w.RcvDoneCh:
+				return
+			}
+		}
+	}()
+	t.Cleanup(w.Stop)
+	return w
+}
+
+// Runs the queued jobs.
+func runJobs(w *Watcher) {
+	for len(w.jobs.queue) > 0 {
+		w.runJob(<-w.jobs.queue)
+	}
+}
+
+// Sends the webhook request to the handler and returns the response.
+func sendHook(handler http.HandlerFunc, repo, body string, headers map[string]string) *httptest.ResponseRecorder {
+	rq := httptest.NewRequest(http.MethodPost, "/"+repo+"/hook", strings.NewReader(body))
+	for key, value := range headers {
+		rq.Header.Set(key, value)
+	}
+	rq = mux.SetURLVars(rq, map[string]string{"repository": repo})
+	rw := httptest.NewRecorder()
+	handler(rw, rq)
+	return rw
+}
+
+func sign(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookSignature(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	repo.config.Hooks = []*config.Hook{{Type: "webhook", Secret: "s3cr3t"}}
+	w := newWebhookWatcher(t, repo)
+	body := `{"ref": "refs/heads/main"}`
+
+	rw := sendHook(w.hookHandler(githubProvider{}), "example", body, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", body, map[string]string{
+		"X-Github-Event":      "push",
+		"X-Hub-Signature-256": sign(body, "wrong"),
+	})
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	assert.Empty(t, repo.pulls)
+
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", body, map[string]string{
+		"X-Github-Event":      "push",
+		"X-Hub-Signature-256": sign(body, "s3cr3t"),
+	})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", body, map[string]string{
+		"X-Github-Event":    "push",
+		"X-Gitea-Signature": strings.TrimPrefix(sign(body, "s3cr3t"), "sha256="),
+	})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+
+	rw = sendHook(w.hookHandler(gitlabProvider{}), "example", body, map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = sendHook(w.hookHandler(gitlabProvider{}), "example", body, map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "s3cr3t"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+
+	stash := `{"refChanges": [{"refId": "refs/heads/main"}]}`
+	rw = sendHook(w.hookHandler(stashProvider{}), "example", stash, nil)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = sendHook(w.hookHandler(stashProvider{}), "example", stash, map[string]string{"X-Hub-Signature": sign(stash, "s3cr3t")})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+
+	runJobs(w)
+	assert.Equal(t, []string{"main", "main", "main", "main"}, repo.pulls)
+}
+
+func TestWebhookWithoutSecret(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	w := newWebhookWatcher(t, repo)
+
+	rw := sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": "refs/heads/main"}`, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"main"}, repo.pulls)
+}
+
+func TestWebhookMultipleRefs(t *testing.T) {
+	repo := newFakeRepo("example", "main", "develop")
+	w := newWebhookWatcher(t, repo)
+
+	stash := `{"refChanges": [
+		{"refId": "refs/heads/main"},
+		{"refId": "refs/heads/develop"},
+		{"refId": "refs/heads/main"},
+		{"refId": "refs/heads/feature"},
+		{"refId": "refs/tags/v1.0.0"}
+	]}`
+	rw := sendHook(w.hookHandler(stashProvider{}), "example", stash, nil)
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"main", "develop"}, repo.pulls)
+	// The change of the repository is notified once
+	assert.Equal(t, 1, repo.changes)
+
+	repo.pulls = nil
+	bitbucket := `{"push": {"changes": [
+		{"new": {"type": "branch", "name": "develop"}},
+		{"new": {"type": "tag", "name": "v1.0.0"}},
+		{"new": null}
+	]}}`
+	rw = sendHook(w.hookHandler(bitbucketProvider{}), "example", bitbucket, map[string]string{"X-Event-Key": "repo:push"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"develop"}, repo.pulls)
+}
+
+func TestWebhookBranchPattern(t *testing.T) {
+	repo := newFakeRepo("example", "release/*")
+	w := newWebhookWatcher(t, repo)
+
+	rw := sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": "refs/heads/release/1.2"}`, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": "refs/heads/release/1.2/hotfix"}`, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Equal(t, []string{"release/1.2"}, repo.pulls)
+	assert.Equal(t, 1, repo.changes)
+
+	// The pushed tag is fetched by the job
+	repo.pulls = nil
+	repo.fetched = []plumbing.ReferenceName{"refs/tags/v1.0.0"}
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": "refs/tags/v1.0.0"}`, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	var job Job
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &job))
+	runJobs(w)
+	assert.Empty(t, repo.pulls)
+	assert.Equal(t, 2, repo.changes)
+	job, _ = w.jobs.get(job.ID)
+	assert.Equal(t, JobSucceeded, job.Status)
+	assert.Equal(t, []string{"Fetched: example/v1.0.0"}, job.Messages)
+}
+
+func TestWebhookBitbucketWithoutBranch(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	repo.config.Tags = []string{"v*"}
+	w := newWebhookWatcher(t, repo)
+	headers := map[string]string{"X-Event-Key": "repo:push"}
+
+	// The deleted branch is not pulled
+	rw := sendHook(w.hookHandler(bitbucketProvider{}), "example", `{"push": {"changes": [{"new": null}]}}`, headers)
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Empty(t, repo.pulls)
+	assert.Equal(t, 0, repo.changes)
+
+	// The pushed tag is fetched by the job
+	repo.fetched = []plumbing.ReferenceName{"refs/tags/v1.0.0"}
+	rw = sendHook(w.hookHandler(bitbucketProvider{}), "example", `{"push": {"changes": [{"new": {"type": "tag", "name": "v1.0.0"}}]}}`, headers)
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	runJobs(w)
+	assert.Empty(t, repo.pulls)
+	assert.Equal(t, 1, repo.changes)
+}
+
+func TestWebhookMalformedPayload(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	w := newWebhookWatcher(t, repo)
+
+	rw := sendHook(w.hookHandler(stashProvider{}), "example", `{"refChanges": []}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	rw = sendHook(w.hookHandler(stashProvider{}), "example", `{"refChanges": [{"refId": ""}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	rw = sendHook(w.hookHandler(bitbucketProvider{}), "example", `{"push": {}}`, map[string]string{"X-Event-Key": "repo:push"})
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": `, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	rw = sendHook(w.hookHandler(gitlabProvider{}), "example", `{}`, map[string]string{"X-Gitlab-Event": "Push Hook"})
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Empty(t, repo.pulls)
+}
+
+func TestWebhookRepositoryLookup(t *testing.T) {
+	repos := []*fakeRepo{newFakeRepo("zeta", "main"), newFakeRepo("alpha", "main"), newFakeRepo("mid", "main")}
+	w := newWebhookWatcher(t, repos[0], repos[1], repos[2])
+
+	for _, repo := range repos {
+		rw := sendHook(w.hookHandler(githubProvider{}), repo.name, `{"ref": "refs/heads/main"}`, map[string]string{"X-Github-Event": "push"})
+		assert.Equal(t, http.StatusAccepted, rw.Code)
+		runJobs(w)
+		assert.Equal(t, []string{"main"}, repo.pulls)
+	}
+
+	rw := sendHook(w.hookHandler(githubProvider{}), "unknown", `{"ref": "refs/heads/main"}`, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	assert.Contains(t, rw.Body.String(), `Repository "unknown" not found`)
+}
+
+func TestWebhookJobs(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	w := newWebhookWatcher(t, repo)
+	getJob := func(id string) (*httptest.ResponseRecorder, Job) {
+		rq := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/jobs/"+id, nil), map[string]string{"id": id})
+		rw := httptest.NewRecorder()
+		w.jobHandler(rw, rq)
+		var job Job
+		json.Unmarshal(rw.Body.Bytes(), &job) //nolint:errcheck
+		return rw, job
+	}
+
+	rw := sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": "refs/heads/main"}`, map[string]string{"X-Github-Event": "push"})
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	var queued Job
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &queued))
+	assert.Equal(t, JobQueued, queued.Status)
+	assert.Equal(t, "/jobs/"+queued.ID, rw.Header().Get("Location"))
+
+	runJobs(w)
+	rw, job := getJob(queued.ID)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, JobSucceeded, job.Status)
+	assert.Equal(t, []string{"main"}, job.Branches)
+	assert.Equal(t, []string{"Changed: example/main"}, job.Messages)
+	assert.NotNil(t, job.Finished)
+
+	// The failure of the KV update fails the job
+	repo.kvErr = errors.New("consul is down")
+	rw = sendHook(w.hookHandler(githubProvider{}), "example", `{"ref": "refs/heads/main"}`, map[string]string{"X-Github-Event": "push"})
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &queued))
+	runJobs(w)
+	_, job = getJob(queued.ID)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Equal(t, "consul is down", job.Error)
+
+	rw, _ = getJob("unknown")
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestWebhookMetrics(t *testing.T) {
+	repo := newFakeRepo("example", "main")
+	w := newWebhookWatcher(t, repo)
+	w.Metrics = metrics.New()
+
+	handler := w.instrument("github", w.hookHandler(githubProvider{}))
+	sendHook(handler, "example", `{"ref": "refs/heads/main"}`, map[string]string{"X-Github-Event": "push"})
+	sendHook(handler, "example", `{"ref": `, map[string]string{"X-Github-Event": "push"})
+	sendHook(handler, "example", `{}`, map[string]string{"X-Github-Event": "ping"})
+	runJobs(w)
+
+	rw := httptest.NewRecorder()
+	w.Metrics.Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rw.Body.String()
+	assert.Contains(t, body, `git2consul_webhook_requests_total{code="200",provider="github"} 1`)
+	assert.Contains(t, body, `git2consul_webhook_requests_total{code="202",provider="github"} 1`)
+	assert.Contains(t, body, `git2consul_webhook_requests_total{code="400",provider="github"} 1`)
+	assert.Contains(t, body, `git2consul_pulls_total{branch="main",outcome="changed",repository="example"} 1`)
+}